@@ -1,177 +1,347 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"math/rand"
-	"sync"
+	"os"
+	"os/signal"
 	"time"
 
-	"github.com/ryanc414/ctci/pkg/stacks"
+	"github.com/ryanc414/ctci/pkg/callcentre"
+	"github.com/ryanc414/ctci/pkg/montecarlo"
 )
 
 func main() {
-	callCentre := InitCallCentre(6, 3, 1)
-	var wg sync.WaitGroup
-
-	for i := 0; i < 10; i++ {
-		caller := &Caller{id: i}
-		wg.Add(1)
-		go callCentre.HandleCall(&wg, caller)
-	}
-
-	wg.Wait()
-}
-
-type CallCentre struct {
-	respondents *EmployeePool
-	managers    *EmployeePool
-	directors   *EmployeePool
-	callerQueue []Caller
-}
-
-type EmployeeCategory int
-
-const (
-	Respondent = iota
-	Manager
-	Director
-)
+	numRespondents := flag.Int("respondents", 6, "number of respondents")
+	numManagers := flag.Int("managers", 3, "number of managers")
+	numDirectors := flag.Int("directors", 1, "number of directors")
+	numCallers := flag.Int("callers", 20, "number of callers")
+	maxQueueLen := flag.Int(
+		"max-queue", 5, "max callers waiting for an employee (0 for no queue)",
+	)
+	patience := flag.Duration(
+		"patience", 2*time.Second, "how long a caller waits before hanging up",
+	)
+	seed := flag.Int64("seed", 0, "seed for the RNG (0 for random)")
+	traceFile := flag.String(
+		"trace",
+		"",
+		"CSV file of calls to replay, with columns arrival,caller_id,issue,service",
+	)
+	eventsFile := flag.String("events", "", "CSV file to write call events to")
 
-type EmployeePool struct {
-	available *stacks.BasicQueue
-	busy      map[int]*Employee
-	category  EmployeeCategory
-	mutex     sync.Mutex
-}
+	simulate := flag.Bool(
+		"simulate", false, "run a discrete-event simulation on a virtual clock",
+	)
+	hours := flag.Float64("hours", 8, "simulated hours of incoming calls")
+	arrivalRate := flag.Float64("arrival-rate", 60, "mean calls per hour")
+	respondentService := flag.String(
+		"respondent-service", "exp:5m", "respondent handle time distribution",
+	)
+	managerService := flag.String(
+		"manager-service", "exp:10m", "manager handle time distribution",
+	)
+	directorService := flag.String(
+		"director-service", "exp:15m", "director handle time distribution",
+	)
+	simPatience := flag.String(
+		"sim-patience", "exp:3m", "simulated caller patience distribution",
+	)
 
-type Employee struct {
-	id       int
-	category EmployeeCategory
-}
+	reportFormat := flag.String("report", "text", "report format: text or json")
+	serviceLevel := flag.Duration(
+		"service-level", 20*time.Second, "target time to answer a call",
+	)
 
-type Caller struct {
-	id int
-}
+	optimise := flag.Bool(
+		"optimise", false, "find the cheapest staffing that meets -target",
+	)
+	target := flag.Float64(
+		"target", 0.8, "fraction of calls to answer within -service-level",
+	)
+	method := flag.String("method", "erlang", "optimiser method: erlang or sim")
+	respondentCost := flag.Float64("respondent-cost", 20, "respondent hourly cost")
+	managerCost := flag.Float64("manager-cost", 35, "manager hourly cost")
+	directorCost := flag.Float64("director-cost", 60, "director hourly cost")
+	flag.Parse()
+
+	*seed = montecarlo.ResolveSeed(*seed)
+
+	var trace []callcentre.TraceCall
+	if *traceFile != "" {
+		if *optimise {
+			fmt.Fprintln(os.Stderr, "-trace cannot be used with -optimise")
+			os.Exit(1)
+		}
+
+		var err error
+		trace, err = callcentre.ReadTraceFile(*traceFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 
-// Initialise a new CallCentre.
-func InitCallCentre(
-	numRespondents, numManagers, numDirectors int) *CallCentre {
-	return &CallCentre{
-		respondents: InitEmployeePool(numRespondents, Respondent),
-		managers:    InitEmployeePool(numManagers, Manager),
-		directors:   InitEmployeePool(numDirectors, Director),
+	if *eventsFile != "" && *optimise {
+		fmt.Fprintln(os.Stderr, "-events cannot be used with -optimise")
+		os.Exit(1)
 	}
-}
 
-// Initialise a new pool of employees.
-func InitEmployeePool(
-	numEmployees int, category EmployeeCategory,
-) *EmployeePool {
-	available := stacks.NewBasicQueue()
-	for i := 0; i < numEmployees; i++ {
-		available.Add(InitEmployee(i, category))
+	onEvent, closeEvents, err := openEventLog(*eventsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	return &EmployeePool{
-		available: available,
-		busy:      make(map[int]*Employee),
-		category:  category,
+	if *simulate || *optimise {
+		config := callcentre.SimConfig{
+			NumRespondents: *numRespondents,
+			NumManagers:    *numManagers,
+			NumDirectors:   *numDirectors,
+			MaxQueueLen:    *maxQueueLen,
+			ArrivalRate:    *arrivalRate,
+			Duration:       time.Duration(*hours * float64(time.Hour)),
+			Seed:           *seed,
+			Trace:          trace,
+			OnEvent:        onEvent,
+		}
+
+		err := parseDistributions(
+			&config,
+			*respondentService,
+			*managerService,
+			*directorService,
+			*simPatience,
+		)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if *optimise {
+			err = runOptimiser(callcentre.OptimiseConfig{
+				Sim:        config,
+				Target:     *target,
+				Threshold:  *serviceLevel,
+				HourlyCost: [3]float64{*respondentCost, *managerCost, *directorCost},
+				Method:     *method,
+			})
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		stats, err := runSimulation(config)
+		if err == nil {
+			err = closeEvents()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		printReport(stats, *serviceLevel, *reportFormat)
+		return
 	}
-}
 
-// Initialise a new employee.
-func InitEmployee(
-	id int, category EmployeeCategory,
-) *Employee {
-	return &Employee{
-		id:       id,
-		category: category,
+	stats, err := runLive(
+		callcentre.DispatcherConfig{
+			NumRespondents: *numRespondents,
+			NumManagers:    *numManagers,
+			NumDirectors:   *numDirectors,
+			MaxQueueLen:    *maxQueueLen,
+			ServiceTime:    randomServiceTime(*seed),
+			Log:            os.Stdout,
+			OnEvent:        onEvent,
+		},
+		trace,
+		*numCallers,
+		*patience,
+		*seed,
+	)
+	if err == nil {
+		err = closeEvents()
 	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	printReport(stats, *serviceLevel, *reportFormat)
 }
 
-// Handle an incoming call.
-func (callCentre *CallCentre) HandleCall(wg *sync.WaitGroup, caller *Caller) {
-	defer wg.Done()
+// Open a CSV file to log call events to. Returns the callback to pass events
+// to and a function that flushes and closes the file. If filename is empty,
+// events are not logged.
+func openEventLog(
+	filename string,
+) (func(callcentre.CallEvent), func() error, error) {
+	if filename == "" {
+		return nil, func() error { return nil }, nil
+	}
 
-	success := callCentre.respondents.HandleCall(caller)
-	if success {
-		return
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	success = callCentre.managers.HandleCall(caller)
-	if success {
-		return
+	log, err := callcentre.NewEventLogWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
 	}
 
-	success = callCentre.directors.HandleCall(caller)
-	if success {
-		return
+	closeLog := func() error {
+		if err := log.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
 	}
 
-	panic("Could not handle call")
+	return log.Record, closeLog, nil
 }
 
-// Handle a call by assigning it to an available employee. Returns true if the
-// call was successfully handled, false if it needs to be delegated.
-func (pool *EmployeePool) HandleCall(caller *Caller) bool {
-	pool.mutex.Lock()
-	next, err := pool.available.Remove()
-	if err != nil {
-		pool.mutex.Unlock()
-		fmt.Printf(
-			"No available employees in category %v\n",
-			categoryString(pool.category),
-		)
-		return false
+// Dispatch callers in real time. If a trace is given its callers ring in at
+// their recorded arrival times and take their recorded service times,
+// otherwise numCallers random callers all ring at once. Interrupting the
+// program stops any more callers ringing in, but calls already in progress
+// are allowed to finish.
+func runLive(
+	config callcentre.DispatcherConfig,
+	trace []callcentre.TraceCall,
+	numCallers int,
+	patience time.Duration,
+	seed int64,
+) (callcentre.RunStats, error) {
+	if trace != nil {
+		config.ServiceTime = callcentre.TraceServiceTime(trace)
 	}
 
-	employee := next.(*Employee)
-	pool.busy[employee.id] = employee
-	pool.mutex.Unlock()
+	dispatcher, err := callcentre.NewDispatcher(config)
+	if err != nil {
+		return callcentre.RunStats{}, err
+	}
 
-	success := employee.HandleCall(caller)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	pool.mutex.Lock()
-	delete(pool.busy, employee.id)
-	pool.available.Add(employee)
-	pool.mutex.Unlock()
+	if trace != nil {
+		calls := callcentre.ReplayTrace(ctx, trace, callcentre.RealClock{}, patience)
+		return dispatcher.Run(ctx, calls), nil
+	}
 
-	return success
+	calls := make(chan *callcentre.Caller)
+	go func() {
+		defer close(calls)
+
+		rng := montecarlo.NewRand(seed)
+		for i := 0; i < numCallers; i++ {
+			caller := &callcentre.Caller{
+				ID:       i,
+				Issue:    callcentre.RandomIssue(rng),
+				Patience: patience,
+			}
+
+			select {
+			case calls <- caller:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return dispatcher.Run(ctx, calls), nil
 }
 
-// Handle a call as an employee.
-func (employee *Employee) HandleCall(caller *Caller) bool {
-	category := categoryString(employee.category)
-
-	fmt.Printf(
-		"%v #%v handles call from caller %v\n", category, employee.id, caller.id,
-	)
+// Employees spend a random amount of time, up to a second, on each call.
+// Calls are handled concurrently and in no fixed order, so each call draws
+// from its own RNG stream, derived from the seed and the caller and tier who
+// handle it. Escalated calls move up a tier each time, so no two calls share
+// a stream, and the same seed always gives the same service times.
+func randomServiceTime(
+	seed int64,
+) func(employee *callcentre.Employee, caller *callcentre.Caller) time.Duration {
+	return func(
+		employee *callcentre.Employee, caller *callcentre.Caller,
+	) time.Duration {
+		stream := caller.ID*3 + int(employee.Category())
+		rng := montecarlo.NewRand(montecarlo.StreamSeed(seed, stream))
+		return time.Duration(rng.Intn(1000)) * time.Millisecond
+	}
+}
 
-	time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
+// Summarise the stats from a run and print the report.
+func printReport(stats callcentre.RunStats, serviceLevel time.Duration, format string) {
+	report := callcentre.BuildReport(stats, serviceLevel)
+	if err := report.Write(os.Stdout, format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
-	fmt.Printf(
-		"%v #%v finished handling call from caller %v\n",
-		category,
-		employee.id,
-		caller.id,
-	)
+// Parse the distribution flags into a simulation config.
+func parseDistributions(
+	config *callcentre.SimConfig,
+	respondentService, managerService, directorService, patience string,
+) error {
+	var err error
+	for i, spec := range []string{
+		respondentService, managerService, directorService,
+	} {
+		config.ServiceTimes[i], err = callcentre.ParseDistribution(spec)
+		if err != nil {
+			return err
+		}
+	}
 
-	return true
+	config.Patience, err = callcentre.ParseDistribution(patience)
+	return err
 }
 
-// Get the employee's category as a string.
-func categoryString(category EmployeeCategory) string {
-	switch category {
-	case Respondent:
-		return "Respondent"
+// Run a simulation and return the stats collected.
+func runSimulation(
+	config callcentre.SimConfig,
+) (callcentre.RunStats, error) {
+	sim, err := callcentre.NewSimulation(config)
+	if err != nil {
+		return callcentre.RunStats{}, err
+	}
 
-	case Manager:
-		return "Manager"
+	start := time.Now()
+	stats := sim.Run()
+
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if config.Trace != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			"Replayed %v traced calls (seed %v) in %v\n",
+			len(config.Trace),
+			config.Seed,
+			elapsed,
+		)
+	} else {
+		fmt.Fprintf(
+			os.Stderr,
+			"Simulated %v of calls (seed %v) in %v\n",
+			config.Duration,
+			config.Seed,
+			elapsed,
+		)
+	}
 
-	case Director:
-		return "Director"
+	return stats, nil
+}
 
-	default:
-		panic("Unexpected employee category")
+// Run the optimiser and print the plan it finds.
+func runOptimiser(config callcentre.OptimiseConfig) error {
+	plan, err := callcentre.Optimise(config)
+	if err != nil {
+		return err
 	}
+
+	return plan.Write(os.Stdout)
 }
@@ -3,96 +3,126 @@ package main
 import (
 	"bufio"
 	"errors"
+	"flag"
 	"fmt"
-	"math/rand"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ryanc414/ctci/pkg/minesweeper"
+	"github.com/ryanc414/ctci/pkg/montecarlo"
 	"github.com/ryanc414/ctci/pkg/objects"
 )
 
-// Seend the RNG and play a new game.
+// Parse options and play a new game, or resume a saved one.
 func main() {
-	objects.SeedRng()
-	game := InitGame(10, 6)
-	game.Play()
-}
-
-// Represents all game state.
-type Game struct {
-	grid   Grid
-	status GameStatus
-}
+	gridSize := flag.Int("size", 10, "length of each side of the grid")
+	numBombs := flag.Int("bombs", 6, "number of bombs to place")
+	difficulty := flag.String(
+		"difficulty", "", "easy, medium or hard (overrides -size and -bombs)",
+	)
+	seed := flag.Int64("seed", 0, "seed for bomb placement (0 for random)")
+	loadPath := flag.String("load", "", "resume a game saved to this file")
+	savePath := flag.String(
+		"save", "minesweeper_save.json", "file to save the game to",
+	)
+	scoresPath := flag.String(
+		"scores", "minesweeper_scores.json", "file to record best times in",
+	)
+	flag.Parse()
+
+	game, err := initGame(*loadPath, *difficulty, *gridSize, *numBombs, *seed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-// A game can either be in progres, or finished in a win or lose state.
-type GameStatus int
+	if !play(game, *savePath) {
+		return
+	}
 
-const (
-	InProgress = iota
-	GameWon
-	GameLost
-)
+	if err := recordScore(game, *scoresPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
-// Every turn, the user may take one of two actions: explore or flag.
+// Every turn, the user may take one of three actions: explore, flag or save.
 type GameAction int
 
 const (
 	Explore = iota
 	Flag
+	Save
 )
 
-type Grid [][]Cell
-
-// Each cell in the grid may be in a combination of states. We use bitflags
-// to store the states, we could have also stored a struct of bools.
-type Cell int
-
-const (
-	Bomb     = 0x1
-	Explored = 0x2
-	Flagged  = 0x4
-)
+// Load a saved game if a path is given, otherwise start a new one.
+func initGame(
+	loadPath, difficulty string, gridSize, numBombs int, seed int64,
+) (*minesweeper.Game, error) {
+	if loadPath != "" {
+		return minesweeper.LoadFile(loadPath)
+	}
 
-// Initialise a new game object.
-func InitGame(gridSize, numBombs int) *Game {
-	return &Game{
-		grid:   InitGrid(gridSize, numBombs),
-		status: InProgress,
+	if difficulty != "" {
+		preset, err := minesweeper.FindDifficulty(difficulty)
+		if err != nil {
+			return nil, err
+		}
+		gridSize, numBombs = preset.Size, preset.NumBombs
 	}
+
+	return minesweeper.NewGameFromSeed(
+		gridSize, numBombs, montecarlo.ResolveSeed(seed),
+	)
 }
 
-// Play a new game.
-func (game Game) Play() {
-	for game.status == InProgress {
-		game.display()
+// Play a game until it is won or lost. Returns false if the player saved the
+// game and quit before finishing.
+func play(game *minesweeper.Game, savePath string) bool {
+	for game.Status() == minesweeper.InProgress {
+		display(game)
 		action := promptAction()
-		coords := game.getCoordsInput()
-		game.applyAction(action, coords)
-		game.status = game.getStatus()
+
+		if action == Save {
+			if err := game.SaveFile(savePath); err != nil {
+				fmt.Printf("Could not save game: %v\n", err)
+				continue
+			}
+
+			fmt.Printf("Game saved to %v\n", savePath)
+			return false
+		}
+
+		coords := promptCoords()
+		if err := applyAction(game, action, coords); err != nil {
+			fmt.Printf("%v, try again.\n", err)
+		}
 	}
 
-	game.display()
-	game.printEndStatus()
+	display(game)
+	printEndStatus(game)
+	return true
 }
 
 // Display the game.
-func (game Game) display() {
+func display(game *minesweeper.Game) {
 	var builder strings.Builder
 
 	builder.WriteString("  ")
-	for i := range game.grid {
+	for i := 0; i < game.Size(); i++ {
 		builder.WriteString(strconv.Itoa(i))
 		builder.WriteRune(' ')
 	}
 	builder.WriteRune('\n')
 
-	for row := range game.grid {
+	for row := 0; row < game.Size(); row++ {
 		builder.WriteString(strconv.Itoa(row))
 		builder.WriteRune(' ')
 
-		for col := range game.grid[row] {
-			builder.WriteRune(game.grid.CellChar(
+		for col := 0; col < game.Size(); col++ {
+			builder.WriteRune(game.CellChar(
 				objects.GridCoords{Row: row, Col: col},
 			))
 			builder.WriteRune(' ')
@@ -103,160 +133,95 @@ func (game Game) display() {
 	fmt.Print(builder.String())
 }
 
-// Get a valid row and column input from the user.
-func (game Game) getCoordsInput() objects.GridCoords {
-	coords := promptCoords()
-	for !game.grid.validCoords(coords) ||
-		game.grid[coords.Row][coords.Col]&Explored != 0 {
-		fmt.Println("Invalid row/col, try again.")
-		coords = promptCoords()
-	}
-
-	return coords
-}
-
 // Apply a game action to a specified cell.
-func (game Game) applyAction(action GameAction, coords objects.GridCoords) {
+func applyAction(
+	game *minesweeper.Game, action GameAction, coords objects.GridCoords,
+) error {
 	switch action {
 	case Explore:
-		game.grid[coords.Row][coords.Col] |= Explored
-		if game.grid[coords.Row][coords.Col]&Bomb == 0 &&
-			game.grid.countNeighbourBombs(coords) == 0 {
-			game.exploreNeighbours(coords)
-		}
+		_, err := game.Reveal(coords)
+		return err
 
 	case Flag:
-		if game.grid[coords.Row][coords.Col]&Flagged == 0 {
-			game.grid[coords.Row][coords.Col] |= Flagged
-		} else {
-			game.grid[coords.Row][coords.Col] &= ^Flagged
-		}
+		_, err := game.ToggleFlag(coords)
+		return err
 
 	default:
 		panic("Invalid action")
 	}
 }
 
-// Explore all neighbours, when it is known there are no bombs.
-func (game Game) exploreNeighbours(coords objects.GridCoords) {
-	for i := range objects.GridDirections {
-		newCoords := coords.MoveDirection(objects.GridDirections[i])
-		if game.grid.validCoords(newCoords) &&
-			game.grid[newCoords.Row][newCoords.Col]&Explored == 0 {
-			game.applyAction(Explore, newCoords)
-		}
-	}
-}
-
 // Print the outcome at the end of the game.
-func (game Game) printEndStatus() {
-	switch game.status {
-	case GameWon:
+func printEndStatus(game *minesweeper.Game) {
+	switch game.Status() {
+	case minesweeper.GameWon:
 		fmt.Println("You win - congrats!")
 
-	case GameLost:
+	case minesweeper.GameLost:
 		fmt.Println("BOOM: you lose.")
 
 	default:
 		panic("Unexpected game status")
 	}
-}
-
-// Return the current game status based on the grid state.
-func (game Game) getStatus() GameStatus {
-	numUnexplored := 0
 
-	for row := range game.grid {
-		for col := range game.grid {
-			cell := game.grid[row][col]
-
-			if cell&Explored != 0 && cell&Bomb != 0 {
-				return GameLost
-			}
+	fmt.Printf(
+		"Time: %v, moves: %v\n",
+		game.Elapsed().Round(time.Second),
+		game.NumMoves(),
+	)
+}
 
-			if cell&(Explored|Flagged) == 0 {
-				numUnexplored++
-			}
-		}
+// Record the score for a won game and display the best times for its
+// difficulty.
+func recordScore(game *minesweeper.Game, scoresPath string) error {
+	table, err := minesweeper.LoadScores(scoresPath)
+	if err != nil {
+		return err
 	}
 
-	if numUnexplored > 0 {
-		return InProgress
-	} else {
-		return GameWon
-	}
-}
+	difficulty := minesweeper.DifficultyName(game)
 
-// Initialise a new grid.
-func InitGrid(size, numBombs int) Grid {
-	grid := make(Grid, size)
-	for i := range grid {
-		grid[i] = make([]Cell, size)
-	}
+	if game.Status() == minesweeper.GameWon {
+		rank := table.Add(difficulty, minesweeper.Score{
+			Elapsed:  game.Elapsed(),
+			NumMoves: game.NumMoves(),
+			Date:     time.Now(),
+		})
+		if rank > 0 {
+			fmt.Printf("New best time - rank #%v!\n", rank)
+		}
 
-	bombsPlaced := 0
-	for bombsPlaced != numBombs {
-		row := rand.Intn(size)
-		col := rand.Intn(size)
-		if grid[row][col]&Bomb == 0 {
-			grid[row][col] |= Bomb
-			bombsPlaced++
+		if err := table.Save(scoresPath); err != nil {
+			return err
 		}
 	}
 
-	return grid
+	printScores(difficulty, table.Top(difficulty))
+	return nil
 }
 
-// Check if row and column indices are valid.
-func (grid Grid) validCoords(coords objects.GridCoords) bool {
-	if coords.Row < 0 || coords.Row >= len(grid) {
-		return false
+// Display the best scores for a difficulty.
+func printScores(difficulty string, scores []minesweeper.Score) {
+	fmt.Printf("Best times (%v):\n", difficulty)
+	if len(scores) == 0 {
+		fmt.Println("  none yet")
+		return
 	}
 
-	if coords.Col < 0 || coords.Col >= len(grid[0]) {
-		return false
-	}
-
-	return true
-}
-
-// Return a character to represent a cell in the grid.
-func (grid Grid) CellChar(coords objects.GridCoords) rune {
-	cell := grid[coords.Row][coords.Col]
-	if cell&Explored != 0 {
-		if cell&Bomb != 0 {
-			return 'X'
-		} else {
-			neighbourBombs := grid.countNeighbourBombs(coords)
-			if neighbourBombs == 0 {
-				return ' '
-			} else {
-				return []rune(strconv.Itoa(neighbourBombs))[0]
-			}
-		}
-	} else {
-		if cell&Flagged != 0 {
-			return 'F'
-		} else {
-			return '.'
-		}
+	for i, score := range scores {
+		fmt.Printf(
+			"%3d. %8v  %4d moves  %v\n",
+			i+1,
+			score.Elapsed.Round(10*time.Millisecond),
+			score.NumMoves,
+			score.Date.Format("2006-01-02"),
+		)
 	}
 }
 
-// Count the number of neighbouring bombs.
-func (grid Grid) countNeighbourBombs(coords objects.GridCoords) int {
-	bombCount := 0
-
-	for i := range objects.GridDirections {
-		newCoords := coords.MoveDirection(objects.GridDirections[i])
-		if grid.validCoords(newCoords) &&
-			grid[newCoords.Row][newCoords.Col]&Bomb != 0 {
-			bombCount++
-		}
-	}
-
-	return bombCount
-}
+// All prompts share one buffered reader, so that input read ahead by one
+// prompt is not lost to the next.
+var stdinReader = bufio.NewReader(os.Stdin)
 
 // Convert an input string to an Action value.
 func toAction(actionInput string) (GameAction, error) {
@@ -272,6 +237,9 @@ func toAction(actionInput string) (GameAction, error) {
 	case 'F':
 		return Flag, nil
 
+	case 'S':
+		return Save, nil
+
 	default:
 		return -1, errors.New("Invalid action")
 	}
@@ -279,8 +247,8 @@ func toAction(actionInput string) (GameAction, error) {
 
 // Prompt user to select an action.
 func promptAction() GameAction {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Select an action ((E)xplore or (F)lag)\n> ")
+	reader := stdinReader
+	fmt.Print("Select an action ((E)xplore, (F)lag or (S)ave and quit)\n> ")
 	actionInput, err := reader.ReadString('\n')
 	if err != nil {
 		panic(err)
@@ -291,7 +259,7 @@ func promptAction() GameAction {
 	for actionErr != nil {
 		fmt.Print(
 			"Invalid action, please try again.\n" +
-				"Valid actions are (E)xplore or (F)lag\n> ",
+				"Valid actions are (E)xplore, (F)lag or (S)ave and quit\n> ",
 		)
 		actionInput, err := reader.ReadString('\n')
 		if err != nil {
@@ -316,7 +284,7 @@ func promptCoords() objects.GridCoords {
 // Get an integer input from user.
 func getIntInput(prompt string) int {
 	fmt.Print(prompt)
-	reader := bufio.NewReader(os.Stdin)
+	reader := stdinReader
 	rawInput, err := reader.ReadString('\n')
 	if err != nil {
 		panic(err)
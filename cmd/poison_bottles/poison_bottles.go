@@ -1,87 +1,211 @@
 package main
 
 import (
-	crypto_rand "crypto/rand"
-	"encoding/binary"
+	"errors"
+	"flag"
 	"fmt"
-	math_rand "math/rand"
-)
+	"math/rand"
+	"os"
 
-const NUM_BOTTLES = 1000
+	"github.com/ryanc414/ctci/pkg/montecarlo"
+	"github.com/ryanc414/ctci/pkg/poison"
+)
 
 func main() {
-	bottles := make([]bool, NUM_BOTTLES)
-	seedRng()
-	actualIndex := math_rand.Intn(NUM_BOTTLES)
+	numBottles := flag.Int("bottles", 1000, "number of bottles")
+	numStrips := flag.Int("strips", 10, "number of test strips")
+	numPoisoned := flag.Int("poisoned", 1, "number of poisoned bottles")
+	showPlan := flag.Bool(
+		"plan", false, "print which bottles go on which strip and exit",
+	)
+	simulate := flag.Bool(
+		"simulate",
+		false,
+		"compare strategies over many trials, with a 7-day test delay",
+	)
+	trials := flag.Int("trials", 1000, "number of trials to simulate")
+	seed := flag.Int64("seed", 0, "seed for the RNG (0 for random)")
+	flag.Parse()
+
+	if err := run(
+		*numBottles,
+		*numStrips,
+		*numPoisoned,
+		*showPlan,
+		*simulate,
+		*trials,
+		montecarlo.ResolveSeed(*seed),
+	); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Run the mode chosen on the command line.
+func run(
+	numBottles, numStrips, numPoisoned int,
+	showPlan, simulate bool,
+	trials int,
+	seed int64,
+) error {
+	if numBottles < 1 {
+		return errors.New("-bottles must be at least 1")
+	}
+
+	if numStrips < 0 {
+		return errors.New("-strips must not be negative")
+	}
+
+	if numPoisoned < 1 || numPoisoned > numBottles {
+		return fmt.Errorf("-poisoned must be between 1 and %v", numBottles)
+	}
+
+	if simulate {
+		return simulateStrategies(
+			numBottles,
+			numStrips,
+			numPoisoned,
+			montecarlo.Config{Trials: trials, Seed: seed},
+		)
+	}
+
+	rng := montecarlo.NewRand(seed)
+
+	if numPoisoned > 1 {
+		if showPlan {
+			return errors.New("-plan needs -poisoned=1")
+		}
+		return findPoisonBottles(numBottles, numStrips, numPoisoned, rng)
+	}
+
+	// Check there are enough strips before doing anything else.
+	plan, err := poison.DropPlan(numBottles, numStrips)
+	if err != nil {
+		return err
+	}
+
+	if showPlan {
+		printPlan(plan)
+		return nil
+	}
+
+	bottles := make([]bool, numBottles)
+	actualIndex := rng.Intn(numBottles)
 	bottles[actualIndex] = true
 
-	index := FindPoisonBottle(bottles)
+	index, err := FindPoisonBottle(bottles, plan)
+	if err != nil {
+		return err
+	}
+
 	if index != actualIndex {
 		panic(fmt.Sprintf("Got index %v, expected %v", index, actualIndex))
 	}
 
 	fmt.Printf("Found poison bottle at index %v\n", index)
+	return nil
 }
 
-// Seed the RNG so that different results are produced each time.
-func seedRng() {
-	var b [8]byte
-	_, err := crypto_rand.Read(b[:])
+// Find the poisoned bottle in a single round, following a drop plan.
+func FindPoisonBottle(bottles []bool, plan [][]int) (int, error) {
+	lab := poison.NewLab(bottles, len(plan))
+
+	results, err := lab.RunRound(plan)
 	if err != nil {
-		panic("Cannot send RNG")
+		return 0, err
 	}
 
-	math_rand.Seed(int64(binary.LittleEndian.Uint64(b[:])))
+	return poison.DecodeDropResults(results), nil
 }
 
-type TestStrip struct {
-	result bool
+// Print which bottles go on which strip.
+func printPlan(plan [][]int) {
+	for strip, bottles := range plan {
+		fmt.Printf("Strip %v: %v\n", strip, bottles)
+	}
 }
 
-// Find the poisoned bottle using binary search.
-func FindPoisonBottle(bottles []bool) int {
-	results := runTests(bottles)
-	return getBottleIndex(results)
-}
+// Poison several random bottles and find them by adaptive group testing.
+func findPoisonBottles(
+	numBottles, numStrips, numPoisoned int, rng *rand.Rand,
+) error {
+	bottles := make([]bool, numBottles)
+	for _, i := range rng.Perm(numBottles)[:numPoisoned] {
+		bottles[i] = true
+	}
 
-// Test each strip for poison.
-func runTests(bottles []bool) []bool {
-	results := make([]bool, 10)
+	lab := poison.NewLab(bottles, numStrips)
+	found, err := poison.AdaptiveSplitting{K: numPoisoned}.FindPoisons(lab)
+	if err != nil {
+		return err
+	}
 
-	for i := range bottles {
-		addDropsForBottle(results, bottles, i)
+	for _, i := range found {
+		if !bottles[i] {
+			panic(fmt.Sprintf("Bottle %v is not poisoned", i))
+		}
+	}
+	if len(found) != numPoisoned {
+		panic(fmt.Sprintf("Found %v bottles, expected %v", len(found), numPoisoned))
 	}
 
-	return results
+	fmt.Printf(
+		"Found poison bottles at indices %v after %v days, using up %v strips\n",
+		found,
+		lab.Days(),
+		lab.StripsUsed(),
+	)
+	return nil
 }
 
-// Add drops from a single bottle to the test strips.
-func addDropsForBottle(results []bool,
-	bottles []bool,
-	bottleIndex int) {
-	stripIndex := 0
-	indexCopy := bottleIndex
-
-	for indexCopy > 0 {
-		if indexCopy&1 == 1 {
-			results[stripIndex] = (results[stripIndex] || bottles[bottleIndex])
+// Run each strategy many times and print how long they take and how many
+// strips they use up. Strategies that need more strips than there are are
+// skipped.
+func simulateStrategies(
+	numBottles, numStrips, numPoisoned int, config montecarlo.Config,
+) error {
+	var runs []func() (poison.TrialStats, error)
+
+	if numPoisoned == 1 {
+		for _, strategy := range []poison.Strategy{
+			poison.BinaryEncoding{},
+			poison.Splitting{},
+			poison.Splitting{StripsPerRound: 3},
+			poison.Splitting{StripsPerRound: 1},
+		} {
+			strategy := strategy
+			runs = append(runs, func() (poison.TrialStats, error) {
+				return poison.RunTrials(strategy, numBottles, numStrips, config)
+			})
 		}
-		indexCopy >>= 1
-		stripIndex++
 	}
-}
 
-// Get the bottle number from a the results.
-func getBottleIndex(results []bool) int {
-	mask := 1
-	bottleIndex := 0
+	for _, strategy := range []poison.MultiStrategy{
+		poison.Disjunct{K: numPoisoned},
+		poison.AdaptiveSplitting{K: numPoisoned},
+		poison.AdaptiveSplitting{},
+	} {
+		strategy := strategy
+		runs = append(runs, func() (poison.TrialStats, error) {
+			return poison.RunMultiTrials(
+				strategy, numBottles, numStrips, numPoisoned, config,
+			)
+		})
+	}
 
-	for i := range results {
-		if results[i] {
-			bottleIndex += mask
+	var stats []poison.TrialStats
+	for _, run := range runs {
+		s, err := run()
+		if errors.Is(err, poison.ErrNotEnoughStrips) {
+			fmt.Fprintf(os.Stderr, "Skipping %v\n", err)
+			continue
+		}
+		if err != nil {
+			return err
 		}
-		mask <<= 1
+		stats = append(stats, s)
 	}
 
-	return bottleIndex
+	fmt.Printf("Seed: %v\n", config.Seed)
+	return poison.WriteTrialStats(os.Stdout, stats)
 }
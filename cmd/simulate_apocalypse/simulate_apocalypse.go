@@ -1,46 +1,86 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"math/rand"
+	"os"
 
-	"github.com/ryanc414/ctci/pkg/objects"
+	"github.com/ryanc414/ctci/pkg/apocalypse"
+	"github.com/ryanc414/ctci/pkg/montecarlo"
 )
 
-const NUM_TRIALS = 1000000
-
 func main() {
-	objects.SeedRng()
+	policySpec := flag.String(
+		"policy",
+		"first-girl",
+		"when families stop: first-girl, first-boy, both, size:N or girls:N",
+	)
+	boyProbability := flag.Float64(
+		"boy-prob", 0.5, "probability that each child is a boy",
+	)
+	maxChildren := flag.Int(
+		"max-children", 0, "most children a family can have (0 for no limit)",
+	)
+	families := flag.Int("families", 1000000, "number of families to simulate")
+	seed := flag.Int64("seed", 0, "seed for the RNG (0 for random)")
+	workers := flag.Int(
+		"workers", 0, "number of goroutines to simulate with (0 for one per CPU)",
+	)
+	tolerance := flag.Float64(
+		"tolerance",
+		0,
+		"stop once the 95% CI for the fraction of girls is within ± this (0 to simulate every family)",
+	)
+	flag.Parse()
 
-	results := make([]int, NUM_TRIALS)
-	for i := 0; i < NUM_TRIALS; i++ {
-		results[i] = simulateChildren()
+	policy, err := apocalypse.ParsePolicy(*policySpec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	avgNumBoys := findAvg(results)
-	fmt.Printf(
-		"On average, each couple had %v boys for every 1 girl.\n",
-		avgNumBoys,
-	)
-}
+	*seed = montecarlo.ResolveSeed(*seed)
 
-// Simulate how many boys are had for one girl.
-func simulateChildren() int {
-	numBoys := 0
-	for rand.Float64() < 0.5 {
-		numBoys++
+	result, err := apocalypse.Simulate(apocalypse.Config{
+		Policy:         policy,
+		BoyProbability: *boyProbability,
+		MaxChildren:    *maxChildren,
+		Families:       *families,
+		Seed:           *seed,
+		Workers:        *workers,
+		Tolerance:      *tolerance,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	return numBoys
+	fmt.Printf("Seed: %v\n", *seed)
+	printResult(result)
 }
 
-// Find the average of a slice of ints.
-func findAvg(values []int) float64 {
-	var sum float64 = 0.0
-
-	for i := range values {
-		sum += float64(values[i])
-	}
-
-	return sum / float64(len(values))
+// Print a summary of the simulation.
+func printResult(result apocalypse.Result) {
+	fmt.Printf(
+		"On average, each couple had %v boys for every 1 girl.\n",
+		result.BoysPerGirl,
+	)
+	fmt.Printf(
+		"Families: %v, boys: %v, girls: %v\n",
+		result.Families,
+		result.Boys,
+		result.Girls,
+	)
+	fmt.Printf(
+		"Fraction of girls: %.4f (95%% CI %.4f - %.4f)\n",
+		result.GirlFraction,
+		result.GirlFractionCI[0],
+		result.GirlFractionCI[1],
+	)
+	fmt.Printf(
+		"Mean family size: %.4f (95%% CI %.4f - %.4f)\n",
+		result.MeanFamilySize,
+		result.FamilySizeCI[0],
+		result.FamilySizeCI[1],
+	)
 }
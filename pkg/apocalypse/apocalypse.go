@@ -0,0 +1,324 @@
+package apocalypse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/ryanc414/ctci/pkg/montecarlo"
+)
+
+// The children a family has had so far.
+type Family struct {
+	Boys  int
+	Girls int
+}
+
+// Return the number of children in the family.
+func (family Family) Size() int {
+	return family.Boys + family.Girls
+}
+
+// A Policy decides whether a family has another child, given the children
+// they have so far.
+type Policy func(family Family) bool
+
+// Keep having children until the first girl is born.
+func StopAfterFirstGirl(family Family) bool {
+	return family.Girls == 0
+}
+
+// Keep having children until the first boy is born.
+func StopAfterFirstBoy(family Family) bool {
+	return family.Boys == 0
+}
+
+// Keep having children until the family has at least one of each.
+func StopAfterBoth(family Family) bool {
+	return family.Boys == 0 || family.Girls == 0
+}
+
+// Return a policy of having exactly n children.
+func FixedSize(n int) Policy {
+	return func(family Family) bool {
+		return family.Size() < n
+	}
+}
+
+// Return a policy of having children until n girls are born.
+func StopAfterGirls(n int) Policy {
+	return func(family Family) bool {
+		return family.Girls < n
+	}
+}
+
+// Parse a policy from a string: "first-girl", "first-boy", "both",
+// "size:N" or "girls:N".
+func ParsePolicy(spec string) (Policy, error) {
+	switch spec {
+	case "first-girl":
+		return StopAfterFirstGirl, nil
+
+	case "first-boy":
+		return StopAfterFirstBoy, nil
+
+	case "both":
+		return StopAfterBoth, nil
+	}
+
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("unknown policy %q", spec)
+	}
+
+	n, err := strconv.Atoi(parts[1])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid count in policy %q", spec)
+	}
+
+	switch parts[0] {
+	case "size":
+		return FixedSize(n), nil
+
+	case "girls":
+		return StopAfterGirls(n), nil
+
+	default:
+		return nil, fmt.Errorf("unknown policy %q", spec)
+	}
+}
+
+// Settings for a simulation.
+type Config struct {
+	Policy         Policy
+	BoyProbability float64 // probability that each child is a boy
+	MaxChildren    int     // families stop at this many children; zero for no limit
+	Families       int     // number of families to simulate
+	Seed           int64
+	Workers        int // number of goroutines to use; zero for GOMAXPROCS
+
+	// If set, stop early once the 95% confidence interval for the girl
+	// fraction is no wider than ±Tolerance.
+	Tolerance float64
+}
+
+// Check that a config makes sense.
+func (config Config) validate() error {
+	if config.Policy == nil {
+		return errors.New("no policy")
+	}
+
+	if config.BoyProbability < 0 || config.BoyProbability > 1 {
+		return errors.New("boy probability must be between 0 and 1")
+	}
+
+	if config.MaxChildren < 0 {
+		return errors.New("max children must be >= 0")
+	}
+
+	// When every child is the same sex, some policies never stop - such as
+	// first-girl with only boys born - so those families need a size limit.
+	if config.MaxChildren == 0 &&
+		(config.BoyProbability == 0 || config.BoyProbability == 1) &&
+		!stopsWithSameSex(config.Policy, config.BoyProbability == 1) {
+		return errors.New(
+			"policy never stops with this boy probability; set max children",
+		)
+	}
+
+	if config.Families < 1 {
+		return errors.New("must simulate at least one family")
+	}
+
+	if config.Tolerance < 0 {
+		return errors.New("tolerance must be >= 0")
+	}
+
+	return nil
+}
+
+// The most children a family whose children are all the same sex can have
+// before a policy is taken never to stop.
+const maxSameSexChildren = 1 << 20
+
+// Check whether a policy stops for a family whose children are all boys, or
+// all girls. When the boy probability is 0 or 1, every family is like this,
+// so the policy can be asked directly.
+func stopsWithSameSex(policy Policy, boys bool) bool {
+	var family Family
+	for family.Size() <= maxSameSexChildren {
+		if !policy(family) {
+			return true
+		}
+
+		if boys {
+			family.Boys++
+		} else {
+			family.Girls++
+		}
+	}
+
+	return false
+}
+
+// Simulate a single family, following the policy until it says to stop or
+// the family reaches the maximum size.
+func simulateFamily(config Config, rng *rand.Rand) Family {
+	var family Family
+
+	for config.Policy(family) &&
+		(config.MaxChildren == 0 || family.Size() < config.MaxChildren) {
+		if rng.Float64() < config.BoyProbability {
+			family.Boys++
+		} else {
+			family.Girls++
+		}
+	}
+
+	return family
+}
+
+// Running totals over many families. Every total is an integer, so tallies
+// can be combined exactly in any order.
+type tally struct {
+	families  int64
+	boys      int64
+	girls     int64
+	sizeSq    int64 // sum of squared family sizes
+	girlsSq   int64 // sum of squared numbers of girls
+	girlsSize int64 // sum of girls times family size
+}
+
+// Add the totals from another tally.
+func (t *tally) Merge(acc montecarlo.Accumulator) {
+	other := acc.(*tally)
+	t.families += other.families
+	t.boys += other.boys
+	t.girls += other.girls
+	t.sizeSq += other.sizeSq
+	t.girlsSq += other.girlsSq
+	t.girlsSize += other.girlsSize
+}
+
+// Add a family to the tally.
+func (t *tally) add(family Family) {
+	size := int64(family.Size())
+	girls := int64(family.Girls)
+
+	t.families++
+	t.boys += int64(family.Boys)
+	t.girls += girls
+	t.sizeSq += size * size
+	t.girlsSq += girls * girls
+	t.girlsSize += girls * size
+}
+
+// Summary of a simulation.
+type Result struct {
+	Families int64
+	Boys     int64
+	Girls    int64
+
+	// The fraction of all children who are girls, with a 95% confidence
+	// interval.
+	GirlFraction   float64
+	GirlFractionCI [2]float64
+
+	// Boys born for every girl. Infinite if no girls were born, or NaN if no
+	// children were born at all.
+	BoysPerGirl float64
+
+	// The mean number of children per family, with a 95% confidence interval.
+	MeanFamilySize float64
+	FamilySizeCI   [2]float64
+}
+
+// Summarise a tally.
+func (t tally) result() Result {
+	result := Result{
+		Families:    t.families,
+		Boys:        t.boys,
+		Girls:       t.girls,
+		BoysPerGirl: float64(t.boys) / float64(t.girls),
+	}
+
+	n := float64(t.families)
+	children := float64(t.boys + t.girls)
+
+	// Family sizes are independent, so the usual interval for a mean
+	// applies.
+	meanSize := children / n
+	result.MeanFamilySize = meanSize
+	if t.families > 1 {
+		variance := (float64(t.sizeSq) - n*meanSize*meanSize) / (n - 1)
+		halfWidth := montecarlo.Z95 * math.Sqrt(math.Max(variance, 0)/n)
+		result.FamilySizeCI = [2]float64{meanSize - halfWidth, meanSize + halfWidth}
+	} else {
+		result.FamilySizeCI = [2]float64{meanSize, meanSize}
+	}
+
+	// Children within a family are not independent of each other, since the
+	// policy depends on earlier children, but families are. The girl
+	// fraction is a ratio of two per-family totals, so its standard error
+	// comes from the residuals girls - ratio*size, summed over families.
+	if children > 0 {
+		ratio := float64(t.girls) / children
+		result.GirlFraction = ratio
+
+		residualSq := float64(t.girlsSq) -
+			2*ratio*float64(t.girlsSize) +
+			ratio*ratio*float64(t.sizeSq)
+		halfWidth := 0.0
+		if t.families > 1 {
+			halfWidth = montecarlo.Z95 * math.Sqrt(
+				math.Max(residualSq, 0)/(n*(n-1)),
+			) / meanSize
+		}
+		result.GirlFractionCI = [2]float64{ratio - halfWidth, ratio + halfWidth}
+	}
+
+	return result
+}
+
+// Families are simulated in chunks of this many, each with its own RNG
+// stream.
+const chunkSize = montecarlo.DefaultChunkSize
+
+// Run a simulation of many families following the same policy. The families
+// are simulated in parallel, but the results only depend on the seed.
+func Simulate(config Config) (Result, error) {
+	if err := config.validate(); err != nil {
+		return Result{}, err
+	}
+
+	runConfig := montecarlo.Config{
+		Trials:    config.Families,
+		Seed:      config.Seed,
+		Workers:   config.Workers,
+		ChunkSize: chunkSize,
+	}
+	if config.Tolerance > 0 {
+		runConfig.Converged = func(acc montecarlo.Accumulator) bool {
+			t := acc.(*tally)
+			ci := t.result().GirlFractionCI
+			return t.families > 1 && (ci[1]-ci[0])/2 <= config.Tolerance
+		}
+	}
+
+	acc, err := montecarlo.Run(
+		runConfig,
+		func() montecarlo.Accumulator { return new(tally) },
+		func(rng *rand.Rand, acc montecarlo.Accumulator) error {
+			acc.(*tally).add(simulateFamily(config, rng))
+			return nil
+		},
+	)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return acc.(*tally).result(), nil
+}
@@ -0,0 +1,252 @@
+package apocalypse
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicies(t *testing.T) {
+	assert.True(t, StopAfterFirstGirl(Family{Boys: 3}))
+	assert.False(t, StopAfterFirstGirl(Family{Boys: 3, Girls: 1}))
+
+	assert.True(t, StopAfterFirstBoy(Family{Girls: 2}))
+	assert.False(t, StopAfterFirstBoy(Family{Boys: 1}))
+
+	assert.True(t, StopAfterBoth(Family{Girls: 2}))
+	assert.False(t, StopAfterBoth(Family{Boys: 1, Girls: 1}))
+
+	assert.True(t, FixedSize(2)(Family{Boys: 1}))
+	assert.False(t, FixedSize(2)(Family{Boys: 1, Girls: 1}))
+
+	assert.True(t, StopAfterGirls(2)(Family{Boys: 4, Girls: 1}))
+	assert.False(t, StopAfterGirls(2)(Family{Girls: 2}))
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, spec := range []string{"first-girl", "first-boy", "both", "size:3", "girls:2"} {
+		policy, err := ParsePolicy(spec)
+		require.NoError(t, err, spec)
+		assert.NotNil(t, policy)
+	}
+
+	policy, err := ParsePolicy("size:3")
+	require.NoError(t, err)
+	assert.True(t, policy(Family{Boys: 2}))
+	assert.False(t, policy(Family{Boys: 2, Girls: 1}))
+
+	for _, spec := range []string{"", "random", "size", "size:0", "size:x", "boys:2"} {
+		_, err := ParsePolicy(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSimulateFamily(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// With only boys born and no limit, nobody would ever stop.
+	family := simulateFamily(Config{
+		Policy:         StopAfterFirstGirl,
+		BoyProbability: 1,
+		MaxChildren:    5,
+	}, rng)
+	assert.Equal(t, Family{Boys: 5}, family)
+
+	family = simulateFamily(Config{
+		Policy:         StopAfterFirstGirl,
+		BoyProbability: 0,
+	}, rng)
+	assert.Equal(t, Family{Girls: 1}, family)
+}
+
+// Stopping after the first girl doesn't change the gender ratio: each birth
+// is still equally likely to be a boy or a girl.
+func TestSimulateFirstGirl(t *testing.T) {
+	result, err := Simulate(Config{
+		Policy:         StopAfterFirstGirl,
+		BoyProbability: 0.5,
+		Families:       100000,
+		Seed:           1,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100000), result.Families)
+	assert.Equal(t, int64(100000), result.Girls)
+	assert.InDelta(t, 0.5, result.GirlFraction, 0.01)
+	assert.Less(t, result.GirlFractionCI[0], 0.5)
+	assert.Greater(t, result.GirlFractionCI[1], 0.5)
+	assert.InDelta(t, 1, result.BoysPerGirl, 0.02)
+
+	// Family sizes are geometric with mean 2.
+	assert.InDelta(t, 2, result.MeanFamilySize, 0.02)
+	assert.Less(t, result.FamilySizeCI[0], 2.0)
+	assert.Greater(t, result.FamilySizeCI[1], 2.0)
+}
+
+func TestSimulateFixedSize(t *testing.T) {
+	result, err := Simulate(Config{
+		Policy:         FixedSize(3),
+		BoyProbability: 0.6,
+		Families:       50000,
+		Seed:           2,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3.0, result.MeanFamilySize)
+	assert.Equal(t, [2]float64{3, 3}, result.FamilySizeCI)
+	assert.InDelta(t, 0.4, result.GirlFraction, 0.01)
+	assert.Less(t, result.GirlFractionCI[0], 0.4)
+	assert.Greater(t, result.GirlFractionCI[1], 0.4)
+}
+
+func TestSimulateDeterministic(t *testing.T) {
+	config := Config{
+		Policy:         StopAfterBoth,
+		BoyProbability: 0.5,
+		MaxChildren:    4,
+		Families:       1000,
+		Seed:           3,
+	}
+
+	first, err := Simulate(config)
+	require.NoError(t, err)
+	second, err := Simulate(config)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.LessOrEqual(t, first.MeanFamilySize, 4.0)
+}
+
+func TestSimulateValidation(t *testing.T) {
+	valid := Config{Policy: StopAfterFirstGirl, BoyProbability: 0.5, Families: 1}
+	_, err := Simulate(valid)
+	require.NoError(t, err)
+
+	for _, modify := range []func(*Config){
+		func(c *Config) { c.Policy = nil },
+		func(c *Config) { c.BoyProbability = 1.5 },
+		func(c *Config) { c.MaxChildren = -1 },
+		func(c *Config) { c.Families = 0 },
+		func(c *Config) { c.Tolerance = -1 },
+		func(c *Config) { c.BoyProbability = 1 },
+	} {
+		config := valid
+		modify(&config)
+		_, err := Simulate(config)
+		assert.Error(t, err)
+	}
+
+	// Families where every child is the same sex are fine with a size limit.
+	for _, boyProbability := range []float64{0, 1} {
+		config := valid
+		config.BoyProbability = boyProbability
+		config.MaxChildren = 3
+		_, err := Simulate(config)
+		assert.NoError(t, err)
+	}
+
+	// Without a size limit, only policies that can never stop are rejected.
+	for _, test := range []struct {
+		policy         string
+		boyProbability float64
+		stops          bool
+	}{
+		{"first-girl", 1, false},
+		{"first-girl", 0, true},
+		{"first-boy", 1, true},
+		{"first-boy", 0, false},
+		{"both", 1, false},
+		{"size:3", 1, true},
+		{"size:3", 0, true},
+		{"girls:2", 0, true},
+		{"girls:2", 1, false},
+	} {
+		policy, err := ParsePolicy(test.policy)
+		require.NoError(t, err)
+
+		config := valid
+		config.Policy = policy
+		config.BoyProbability = test.boyProbability
+		_, err = Simulate(config)
+		if test.stops {
+			assert.NoError(t, err, test)
+		} else {
+			assert.Error(t, err, test)
+		}
+	}
+}
+
+// The results depend only on the seed, not on the number of workers.
+func TestSimulateWorkers(t *testing.T) {
+	config := Config{
+		Policy:         StopAfterFirstGirl,
+		BoyProbability: 0.5,
+		Families:       5*chunkSize + 123,
+		Seed:           4,
+		Workers:        1,
+	}
+
+	expected, err := Simulate(config)
+	require.NoError(t, err)
+	assert.Equal(t, int64(config.Families), expected.Families)
+
+	for _, workers := range []int{0, 2, 3, 8, 100} {
+		config.Workers = workers
+		result, err := Simulate(config)
+		require.NoError(t, err)
+		assert.Equal(t, expected, result, "%v workers", workers)
+	}
+
+	config.Seed = 5
+	other, err := Simulate(config)
+	require.NoError(t, err)
+	assert.NotEqual(t, expected, other)
+}
+
+// Test that simulations stop early once the girl fraction is precise
+// enough.
+func TestSimulateTolerance(t *testing.T) {
+	config := Config{
+		Policy:         StopAfterFirstGirl,
+		BoyProbability: 0.5,
+		Families:       100 * chunkSize,
+		Seed:           6,
+		Tolerance:      0.005,
+	}
+
+	result, err := Simulate(config)
+	require.NoError(t, err)
+	assert.Less(t, result.Families, int64(config.Families))
+	assert.Zero(t, result.Families%chunkSize)
+	assert.LessOrEqual(
+		t, (result.GirlFractionCI[1]-result.GirlFractionCI[0])/2, 0.005,
+	)
+
+	config.Workers = 3
+	other, err := Simulate(config)
+	require.NoError(t, err)
+	assert.Equal(t, result, other)
+}
+
+func BenchmarkSimulate(b *testing.B) {
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%v", workers), func(b *testing.B) {
+			config := Config{
+				Policy:         StopAfterFirstGirl,
+				BoyProbability: 0.5,
+				Families:       200000,
+				Seed:           1,
+				Workers:        workers,
+			}
+
+			for i := 0; i < b.N; i++ {
+				if _, err := Simulate(config); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package callcentre
+
+import (
+	"container/list"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A call centre has three tiers of employees. Calls are answered by the
+// lowest eligible tier with a free employee. If nobody is free, callers wait
+// in a FIFO queue until an employee is released or they run out of patience.
+// Employees who cannot resolve a call escalate it to the next tier.
+//
+// Employees may also have skills, such as the languages they speak or the
+// products they support, and callers may need particular skills. A caller is
+// only put through to an employee with every skill they need, unless nobody
+// in the eligible tiers has them, in which case the call is routed by tier
+// alone.
+//
+// The same rules are implemented twice: the Dispatcher handles calls
+// concurrently as they arrive, while the Simulation replays them against a
+// virtual clock.
+type EmployeeCategory int
+
+const (
+	Respondent EmployeeCategory = iota
+	Manager
+	Director
+)
+
+// Get the category as a string.
+func (category EmployeeCategory) String() string {
+	switch category {
+	case Respondent:
+		return "Respondent"
+
+	case Manager:
+		return "Manager"
+
+	case Director:
+		return "Director"
+
+	default:
+		panic("Unexpected employee category")
+	}
+}
+
+// A skill that an employee may have and a caller may need, e.g. "lang:fr" or
+// "product:broadband".
+type Skill string
+
+type Employee struct {
+	id       int
+	category EmployeeCategory
+	skills   map[Skill]bool
+}
+
+// Initialise a new employee.
+func InitEmployee(
+	id int, category EmployeeCategory, skills ...Skill,
+) *Employee {
+	employee := &Employee{
+		id:       id,
+		category: category,
+		skills:   make(map[Skill]bool),
+	}
+
+	for _, skill := range skills {
+		employee.skills[skill] = true
+	}
+
+	return employee
+}
+
+// Return the employee's ID, which is unique within their category.
+func (employee *Employee) ID() int {
+	return employee.id
+}
+
+// Return the employee's category.
+func (employee *Employee) Category() EmployeeCategory {
+	return employee.category
+}
+
+// Return the employee's skills, in sorted order.
+func (employee *Employee) Skills() []Skill {
+	skills := make([]Skill, 0, len(employee.skills))
+	for skill := range employee.skills {
+		skills = append(skills, skill)
+	}
+	sort.Slice(skills, func(i, j int) bool { return skills[i] < skills[j] })
+
+	return skills
+}
+
+// Check whether the employee has all of the given skills.
+func (employee *Employee) HasSkills(skills []Skill) bool {
+	for _, skill := range skills {
+		if !employee.skills[skill] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check whether the employee is senior enough to resolve a caller's issue.
+func (employee *Employee) resolves(caller *Caller) bool {
+	return employee.category >= RequiredCategory(caller.Issue)
+}
+
+type Caller struct {
+	ID       int
+	Issue    IssueType
+	Patience time.Duration // how long to wait in the queue before hanging up
+	Skills   []Skill       // skills needed by any employee who takes the call
+
+	// The lowest category of employee that may take the call. This starts at
+	// Respondent and is raised each time the call is escalated.
+	minCategory EmployeeCategory
+
+	// The skills actually required when routing the call. This is either
+	// Skills, or nil if nobody in the eligible tiers has them.
+	routeSkills []Skill
+
+	history []*Employee // employees who handled the call, in order
+}
+
+// Return the employees who have handled the call so far, in order.
+func (caller *Caller) History() []*Employee {
+	return caller.history
+}
+
+// Escalate a call to the tier above the employee who could not resolve it.
+func (caller *Caller) escalate(employee *Employee) {
+	if employee.category == Director {
+		panic("Cannot escalate beyond a director")
+	}
+
+	caller.minCategory = employee.category + 1
+}
+
+// Check whether an employee is eligible to take a queued caller.
+func canHandle(employee *Employee, caller *Caller) bool {
+	return employee.category >= caller.minCategory &&
+		employee.HasSkills(caller.routeSkills)
+}
+
+// The kind of issue a caller is ringing about. Each issue type can only be
+// resolved by employees of a certain category or above.
+type IssueType int
+
+const (
+	GeneralEnquiry IssueType = iota
+	TechnicalFault
+	Complaint
+)
+
+// Get the issue type as a string.
+func (issue IssueType) String() string {
+	switch issue {
+	case GeneralEnquiry:
+		return "GeneralEnquiry"
+
+	case TechnicalFault:
+		return "TechnicalFault"
+
+	case Complaint:
+		return "Complaint"
+
+	default:
+		panic("Unexpected issue type")
+	}
+}
+
+// Short names for each issue type, accepted by ParseIssueType.
+var issueShortNames = [...]string{
+	GeneralEnquiry: "general",
+	TechnicalFault: "technical",
+	Complaint:      "complaint",
+}
+
+// Parse an issue type from its name or short name, ignoring case, or from its
+// number.
+func ParseIssueType(s string) (IssueType, error) {
+	for i, shortName := range issueShortNames {
+		issue := IssueType(i)
+		if strings.EqualFold(s, issue.String()) ||
+			strings.EqualFold(s, shortName) ||
+			s == strconv.Itoa(i) {
+			return issue, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown issue type %q", s)
+}
+
+// Return the lowest category of employee able to resolve an issue.
+func RequiredCategory(issue IssueType) EmployeeCategory {
+	switch issue {
+	case GeneralEnquiry:
+		return Respondent
+
+	case TechnicalFault:
+		return Manager
+
+	case Complaint:
+		return Director
+
+	default:
+		panic("Unexpected issue type")
+	}
+}
+
+// Relative frequency of each issue type. Most calls are general enquiries
+// that any respondent can resolve.
+var issueWeights = [...]int{
+	GeneralEnquiry: 7,
+	TechnicalFault: 2,
+	Complaint:      1,
+}
+
+// Pick a random issue type, according to issueWeights.
+func RandomIssue(rng *rand.Rand) IssueType {
+	total := 0
+	for _, weight := range issueWeights {
+		total += weight
+	}
+
+	n := rng.Intn(total)
+	for issue, weight := range issueWeights {
+		if n < weight {
+			return IssueType(issue)
+		}
+		n -= weight
+	}
+
+	panic("Unreachable")
+}
+
+// Return the fraction of calls that need an employee of at least the given
+// category to resolve them.
+func EscalationFraction(category EmployeeCategory) float64 {
+	total, reaching := 0, 0
+	for issue, weight := range issueWeights {
+		total += weight
+		if RequiredCategory(IssueType(issue)) >= category {
+			reaching += weight
+		}
+	}
+
+	return float64(reaching) / float64(total)
+}
+
+// A pool of employees within a single category. Pools are not safe for
+// concurrent use by themselves - callers must provide their own locking.
+type EmployeePool struct {
+	employees []*Employee // everyone in the pool, busy or not, by ID
+	category  EmployeeCategory
+
+	// Idle employees are kept in the order they became idle, both in a single
+	// list and in a list per skill. To find the longest-idle employee with a
+	// set of skills, only the list for the rarest of those skills is searched.
+	idle      list.List            // of *Employee, longest idle first
+	idleSkill map[Skill]*list.List // of *Employee, longest idle first
+	idleElems map[int]idleElements // positions of each idle employee, by ID
+	busy      map[int]*Employee
+}
+
+// The positions of an idle employee in each of a pool's idle lists.
+type idleElements struct {
+	all    *list.Element
+	skills map[Skill]*list.Element
+}
+
+// Initialise a new pool of employees. Employee i has the skills in skills[i];
+// employees beyond the end of skills have none.
+func InitEmployeePool(
+	numEmployees int, category EmployeeCategory, skills [][]Skill,
+) *EmployeePool {
+	pool := &EmployeePool{
+		employees: make([]*Employee, numEmployees),
+		category:  category,
+		idleSkill: make(map[Skill]*list.List),
+		idleElems: make(map[int]idleElements),
+		busy:      make(map[int]*Employee),
+	}
+
+	for i := range pool.employees {
+		var employeeSkills []Skill
+		if i < len(skills) {
+			employeeSkills = skills[i]
+		}
+
+		pool.employees[i] = InitEmployee(i, category, employeeSkills...)
+		pool.addIdle(pool.employees[i])
+	}
+
+	return pool
+}
+
+// Initialise a pool for each category, in escalation order.
+func initPools(staff [3]int, skills [3][][]Skill) []*EmployeePool {
+	return []*EmployeePool{
+		InitEmployeePool(staff[Respondent], Respondent, skills[Respondent]),
+		InitEmployeePool(staff[Manager], Manager, skills[Manager]),
+		InitEmployeePool(staff[Director], Director, skills[Director]),
+	}
+}
+
+// Check whether anybody in the pool has all of the given skills, whether or
+// not they are busy.
+func (pool *EmployeePool) staffed(skills []Skill) bool {
+	for _, employee := range pool.employees {
+		if employee.HasSkills(skills) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Take the longest-idle employee with all of the given skills from the pool,
+// marking them as busy. Returns false if nobody suitable is available.
+func (pool *EmployeePool) acquire(skills []Skill) (*Employee, bool) {
+	candidates := &pool.idle
+	for _, skill := range skills {
+		withSkill, ok := pool.idleSkill[skill]
+		if !ok {
+			return nil, false
+		}
+
+		if withSkill.Len() < candidates.Len() {
+			candidates = withSkill
+		}
+	}
+
+	for el := candidates.Front(); el != nil; el = el.Next() {
+		employee := el.Value.(*Employee)
+		if employee.HasSkills(skills) {
+			pool.removeIdle(employee)
+			pool.busy[employee.id] = employee
+			return employee, true
+		}
+	}
+
+	return nil, false
+}
+
+// Return a busy employee to the pool. They become the most recently idle
+// employee.
+func (pool *EmployeePool) release(employee *Employee) {
+	delete(pool.busy, employee.id)
+	pool.addIdle(employee)
+}
+
+// Add an employee to the back of the idle lists.
+func (pool *EmployeePool) addIdle(employee *Employee) {
+	elems := idleElements{
+		all:    pool.idle.PushBack(employee),
+		skills: make(map[Skill]*list.Element),
+	}
+
+	for skill := range employee.skills {
+		withSkill, ok := pool.idleSkill[skill]
+		if !ok {
+			withSkill = list.New()
+			pool.idleSkill[skill] = withSkill
+		}
+		elems.skills[skill] = withSkill.PushBack(employee)
+	}
+
+	pool.idleElems[employee.id] = elems
+}
+
+// Remove an employee from the idle lists.
+func (pool *EmployeePool) removeIdle(employee *Employee) {
+	elems := pool.idleElems[employee.id]
+	delete(pool.idleElems, employee.id)
+
+	pool.idle.Remove(elems.all)
+	for skill, el := range elems.skills {
+		withSkill := pool.idleSkill[skill]
+		withSkill.Remove(el)
+		if withSkill.Len() == 0 {
+			delete(pool.idleSkill, skill)
+		}
+	}
+}
+
+// Find a free employee to take a call, marking them as busy. The lowest
+// eligible tier with a suitable employee free is used, and within that tier
+// the employee who has been idle longest. Returns false if nobody suitable is
+// free, in which case the caller's routing skills are left set for matching
+// them against employees as they are released.
+func acquireEmployee(pools []*EmployeePool, caller *Caller) (*Employee, bool) {
+	eligible := pools[caller.minCategory:]
+
+	caller.routeSkills = nil
+	for _, pool := range eligible {
+		if pool.staffed(caller.Skills) {
+			caller.routeSkills = caller.Skills
+			break
+		}
+	}
+
+	for _, pool := range eligible {
+		employee, ok := pool.acquire(caller.routeSkills)
+		if ok {
+			return employee, true
+		}
+	}
+
+	return nil, false
+}
+
+// A source of time. The Dispatcher uses a Clock for all timing, so tests can
+// substitute a fake one and run without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// A Clock that uses the real time.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
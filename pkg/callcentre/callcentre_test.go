@@ -0,0 +1,123 @@
+package callcentre
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmployeeSkills(t *testing.T) {
+	employee := InitEmployee(0, Respondent, "lang:fr", "lang:de")
+
+	assert.Equal(t, []Skill{"lang:de", "lang:fr"}, employee.Skills())
+	assert.True(t, employee.HasSkills(nil))
+	assert.True(t, employee.HasSkills([]Skill{"lang:fr"}))
+	assert.True(t, employee.HasSkills([]Skill{"lang:de", "lang:fr"}))
+	assert.False(t, employee.HasSkills([]Skill{"lang:fr", "product:tv"}))
+}
+
+// Test that the longest-idle employee is chosen.
+func TestPoolLongestIdle(t *testing.T) {
+	pool := InitEmployeePool(3, Respondent, nil)
+
+	first, ok := pool.acquire(nil)
+	require.True(t, ok)
+	assert.Equal(t, 0, first.ID())
+
+	second, ok := pool.acquire(nil)
+	require.True(t, ok)
+	assert.Equal(t, 1, second.ID())
+
+	pool.release(second)
+	pool.release(first)
+
+	// Employee 2 has been idle since the start, then 1 was released before 0.
+	for _, expectedID := range []int{2, 1, 0} {
+		employee, ok := pool.acquire(nil)
+		require.True(t, ok)
+		assert.Equal(t, expectedID, employee.ID())
+	}
+
+	_, ok = pool.acquire(nil)
+	assert.False(t, ok)
+}
+
+// Test that only employees with every required skill are chosen.
+func TestPoolSkills(t *testing.T) {
+	pool := InitEmployeePool(4, Respondent, [][]Skill{
+		{"lang:fr"},
+		{"lang:fr", "product:tv"},
+		{"product:tv"},
+	})
+
+	assert.True(t, pool.staffed([]Skill{"lang:fr", "product:tv"}))
+	assert.False(t, pool.staffed([]Skill{"lang:de"}))
+
+	employee, ok := pool.acquire([]Skill{"product:tv", "lang:fr"})
+	require.True(t, ok)
+	assert.Equal(t, 1, employee.ID())
+
+	_, ok = pool.acquire([]Skill{"product:tv", "lang:fr"})
+	assert.False(t, ok)
+
+	_, ok = pool.acquire([]Skill{"lang:de"})
+	assert.False(t, ok)
+
+	employee, ok = pool.acquire([]Skill{"product:tv"})
+	require.True(t, ok)
+	assert.Equal(t, 2, employee.ID())
+
+	// Employee 3 has no skills, but can take calls that need none.
+	employee, ok = pool.acquire(nil)
+	require.True(t, ok)
+	assert.Equal(t, 0, employee.ID())
+
+	employee, ok = pool.acquire(nil)
+	require.True(t, ok)
+	assert.Equal(t, 3, employee.ID())
+
+	pool.release(InitEmployee(1, Respondent, "lang:fr", "product:tv"))
+	employee, ok = pool.acquire([]Skill{"lang:fr"})
+	require.True(t, ok)
+	assert.Equal(t, 1, employee.ID())
+}
+
+// Test that calls go to the lowest tier with a matching employee free, and
+// fall back to routing by tier when nobody eligible has the skills.
+func TestAcquireEmployee(t *testing.T) {
+	pools := initPools([3]int{2, 1, 1}, [3][][]Skill{
+		Respondent: {nil, {"lang:fr"}},
+		Manager:    {{"lang:de"}},
+	})
+
+	caller := &Caller{Skills: []Skill{"lang:fr"}}
+	employee, ok := acquireEmployee(pools, caller)
+	require.True(t, ok)
+	assert.Equal(t, Respondent, employee.Category())
+	assert.Equal(t, 1, employee.ID())
+
+	// The only French speaker is busy, so the caller must wait for them.
+	_, ok = acquireEmployee(pools, &Caller{Skills: []Skill{"lang:fr"}})
+	assert.False(t, ok)
+
+	// Nobody below director speaks German apart from the manager.
+	caller = &Caller{Skills: []Skill{"lang:de"}}
+	employee, ok = acquireEmployee(pools, caller)
+	require.True(t, ok)
+	assert.Equal(t, Manager, employee.Category())
+
+	// Nobody speaks Spanish, so skills are ignored.
+	caller = &Caller{Skills: []Skill{"lang:es"}}
+	employee, ok = acquireEmployee(pools, caller)
+	require.True(t, ok)
+	assert.Equal(t, Respondent, employee.Category())
+	assert.Equal(t, 0, employee.ID())
+	assert.Nil(t, caller.routeSkills)
+
+	// Once escalated to the directors, nobody eligible speaks French either.
+	caller = &Caller{Skills: []Skill{"lang:fr"}, minCategory: Director}
+	employee, ok = acquireEmployee(pools, caller)
+	require.True(t, ok)
+	assert.Equal(t, Director, employee.Category())
+}
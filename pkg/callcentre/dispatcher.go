@@ -0,0 +1,373 @@
+package callcentre
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Settings for a Dispatcher.
+type DispatcherConfig struct {
+	NumRespondents int
+	NumManagers    int
+	NumDirectors   int
+	MaxQueueLen    int
+
+	// Skills of each employee, by category and then ID. Employees not listed
+	// have no skills.
+	StaffSkills [3][][]Skill
+
+	// How long an employee spends on a call. Required.
+	ServiceTime func(employee *Employee, caller *Caller) time.Duration
+
+	Clock Clock     // defaults to RealClock
+	Log   io.Writer // a line is written for each call event, if set
+
+	// Called for each call event, if set. Calls are never concurrent.
+	OnEvent func(CallEvent)
+}
+
+// A Dispatcher routes incoming calls to employees as they arrive. Each call
+// is handled on its own goroutine, so many calls are in progress at once.
+type Dispatcher struct {
+	config DispatcherConfig
+	clock  Clock
+
+	logMutex sync.Mutex
+	log      io.Writer
+
+	mutex       sync.Mutex
+	pools       []*EmployeePool
+	callerQueue list.List // of *waitingCaller
+
+	// Measurements of every finished call, guarded by the mutex.
+	start    time.Time
+	records  []CallRecord
+	busyTime [3]time.Duration
+
+	inFlight sync.WaitGroup
+}
+
+// A caller waiting in the queue. When an employee is released they are sent
+// directly to the waiting caller over the assigned channel.
+type waitingCaller struct {
+	caller   *Caller
+	assigned chan *Employee
+}
+
+var ErrQueueFull = errors.New("queue is full")
+
+// Initialise a new Dispatcher.
+func NewDispatcher(config DispatcherConfig) (*Dispatcher, error) {
+	if config.ServiceTime == nil {
+		return nil, errors.New("no service time function")
+	}
+
+	clock := config.Clock
+	if clock == nil {
+		clock = RealClock{}
+	}
+
+	log := config.Log
+	if log == nil {
+		log = io.Discard
+	}
+
+	return &Dispatcher{
+		config: config,
+		clock:  clock,
+		log:    log,
+		pools: initPools([3]int{
+			config.NumRespondents, config.NumManagers, config.NumDirectors,
+		}, config.StaffSkills),
+		start: clock.Now(),
+	}, nil
+}
+
+// Accept calls from the channel until it is closed or the context is
+// cancelled. Calls already accepted are then drained: Run waits for every one
+// of them to finish before returning the stats for the run.
+func (dispatcher *Dispatcher) Run(
+	ctx context.Context, calls <-chan *Caller,
+) RunStats {
+	for {
+		select {
+		case <-ctx.Done():
+			dispatcher.inFlight.Wait()
+			return dispatcher.Stats()
+
+		case caller, ok := <-calls:
+			if !ok {
+				dispatcher.inFlight.Wait()
+				return dispatcher.Stats()
+			}
+
+			dispatcher.inFlight.Add(1)
+			go dispatcher.handleCall(caller)
+		}
+	}
+}
+
+// Handle an incoming call. The call is answered straight away if any
+// employee is free, otherwise the caller is queued. Callers are turned away
+// if the queue is full, and hang up if they wait longer than their patience.
+// Calls that the answering employee cannot resolve are escalated and
+// re-queued for the next tier until somebody resolves them.
+func (dispatcher *Dispatcher) handleCall(caller *Caller) {
+	defer dispatcher.inFlight.Done()
+
+	record := CallRecord{
+		CallerID:    caller.ID,
+		Issue:       caller.Issue,
+		Arrival:     dispatcher.sinceStart(),
+		FirstAnswer: -1,
+	}
+	defer func() { dispatcher.addRecord(record) }()
+	dispatcher.event(CallArrived, caller, nil)
+
+	for {
+		employee, waiting, err := dispatcher.assignOrQueue(caller)
+		if err != nil {
+			dispatcher.logf("Caller %v turned away: %v", caller.ID, err)
+			dispatcher.event(CallTurnedAway, caller, nil)
+			record.Outcome = TurnedAway
+			return
+		}
+
+		if employee == nil {
+			dispatcher.logf("Caller %v is waiting in the queue", caller.ID)
+			dispatcher.event(CallQueued, caller, nil)
+			waitStart := dispatcher.clock.Now()
+			employee = dispatcher.awaitEmployee(waiting)
+			record.addWait(dispatcher.clock.Now().Sub(waitStart))
+
+			if employee == nil {
+				dispatcher.logf(
+					"Caller %v hung up after waiting %v", caller.ID, caller.Patience,
+				)
+				dispatcher.event(CallAbandoned, caller, nil)
+				record.Outcome = Abandoned
+				return
+			}
+		}
+
+		if record.FirstAnswer < 0 {
+			record.FirstAnswer = dispatcher.sinceStart() - record.Arrival
+		}
+
+		handleStart := dispatcher.clock.Now()
+		resolved := dispatcher.serve(employee, caller)
+		handleTime := dispatcher.clock.Now().Sub(handleStart)
+		record.Handle += handleTime
+
+		// Report how the call went before the employee moves on to another.
+		if resolved {
+			dispatcher.event(CallResolved, caller, employee)
+		} else {
+			dispatcher.event(CallEscalated, caller, employee)
+		}
+		dispatcher.release(employee, handleTime)
+
+		if resolved {
+			dispatcher.logf(
+				"Call from caller %v resolved, handled by %v",
+				caller.ID,
+				historyString(caller.history),
+			)
+			record.Outcome = Resolved
+			return
+		}
+
+		caller.escalate(employee)
+		record.Escalations++
+		dispatcher.logf(
+			"Call from caller %v escalated to %v", caller.ID, caller.minCategory,
+		)
+	}
+}
+
+// Assign a free employee to a caller. If none are free the caller is added
+// to the back of the queue instead. Callers that have already been escalated
+// were accepted into the call centre earlier, so they are always queued even
+// if the queue is full.
+func (dispatcher *Dispatcher) assignOrQueue(
+	caller *Caller,
+) (*Employee, *waitingCaller, error) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	employee, ok := acquireEmployee(dispatcher.pools, caller)
+	if ok {
+		return employee, nil, nil
+	}
+
+	if len(caller.history) == 0 &&
+		dispatcher.callerQueue.Len() >= dispatcher.config.MaxQueueLen {
+		return nil, nil, ErrQueueFull
+	}
+
+	waiting := &waitingCaller{
+		caller:   caller,
+		assigned: make(chan *Employee, 1),
+	}
+	dispatcher.callerQueue.PushBack(waiting)
+
+	return nil, waiting, nil
+}
+
+// Wait for an employee to be assigned to a queued caller. Returns nil if the
+// caller abandons the call first.
+func (dispatcher *Dispatcher) awaitEmployee(waiting *waitingCaller) *Employee {
+	select {
+	case employee := <-waiting.assigned:
+		return employee
+
+	case <-dispatcher.clock.After(waiting.caller.Patience):
+	}
+
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	// An employee may have been assigned just as the caller gave up - in that
+	// case the call goes ahead.
+	select {
+	case employee := <-waiting.assigned:
+		return employee
+	default:
+	}
+
+	for el := dispatcher.callerQueue.Front(); el != nil; el = el.Next() {
+		if el.Value.(*waitingCaller) == waiting {
+			dispatcher.callerQueue.Remove(el)
+			break
+		}
+	}
+
+	return nil
+}
+
+// Have an employee handle a call. Returns true if the employee resolved the
+// caller's issue, false if it must be escalated to a more senior tier.
+func (dispatcher *Dispatcher) serve(employee *Employee, caller *Caller) bool {
+	caller.history = append(caller.history, employee)
+	dispatcher.logf(
+		"%v #%v handles call from caller %v",
+		employee.category,
+		employee.id,
+		caller.ID,
+	)
+	dispatcher.event(CallAnswered, caller, employee)
+
+	<-dispatcher.clock.After(dispatcher.config.ServiceTime(employee, caller))
+
+	dispatcher.logf(
+		"%v #%v finished handling call from caller %v",
+		employee.category,
+		employee.id,
+		caller.ID,
+	)
+
+	return employee.resolves(caller)
+}
+
+// Release an employee after they spend handleTime on a call. The employee is
+// handed to the longest-waiting eligible caller, or returned to their pool if
+// nobody is waiting.
+func (dispatcher *Dispatcher) release(
+	employee *Employee, handleTime time.Duration,
+) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	dispatcher.busyTime[employee.category] += handleTime
+
+	for el := dispatcher.callerQueue.Front(); el != nil; el = el.Next() {
+		waiting := el.Value.(*waitingCaller)
+		if canHandle(employee, waiting.caller) {
+			dispatcher.callerQueue.Remove(el)
+			waiting.assigned <- employee
+			return
+		}
+	}
+
+	dispatcher.pools[employee.category].release(employee)
+}
+
+// Store the record of a finished call.
+func (dispatcher *Dispatcher) addRecord(record CallRecord) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	dispatcher.records = append(dispatcher.records, record)
+}
+
+// Return the stats for all calls finished so far.
+func (dispatcher *Dispatcher) Stats() RunStats {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	stats := RunStats{
+		Calls: make([]CallRecord, len(dispatcher.records)),
+		Staff: [3]int{
+			dispatcher.config.NumRespondents,
+			dispatcher.config.NumManagers,
+			dispatcher.config.NumDirectors,
+		},
+		BusyTime: dispatcher.busyTime,
+		Elapsed:  dispatcher.sinceStart(),
+	}
+	copy(stats.Calls, dispatcher.records)
+
+	return stats
+}
+
+// Return the time since the dispatcher was created.
+func (dispatcher *Dispatcher) sinceStart() time.Duration {
+	return dispatcher.clock.Now().Sub(dispatcher.start)
+}
+
+// Write a line to the log.
+func (dispatcher *Dispatcher) logf(format string, args ...interface{}) {
+	dispatcher.logMutex.Lock()
+	defer dispatcher.logMutex.Unlock()
+
+	fmt.Fprintf(dispatcher.log, format+"\n", args...)
+}
+
+// Report a call event to the OnEvent callback.
+func (dispatcher *Dispatcher) event(
+	kind CallEventKind, caller *Caller, employee *Employee,
+) {
+	if dispatcher.config.OnEvent == nil {
+		return
+	}
+
+	dispatcher.logMutex.Lock()
+	defer dispatcher.logMutex.Unlock()
+
+	dispatcher.config.OnEvent(CallEvent{
+		Time:     dispatcher.sinceStart(),
+		CallerID: caller.ID,
+		Kind:     kind,
+		Employee: employee,
+	})
+}
+
+// Describe the employees who handled a call, in order.
+func historyString(history []*Employee) string {
+	var builder strings.Builder
+
+	for i, employee := range history {
+		if i > 0 {
+			builder.WriteString(" -> ")
+		}
+		fmt.Fprintf(&builder, "%v #%v", employee.category, employee.id)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,348 @@
+package callcentre
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A fake clock that only moves when advanced. Timers fire when the clock is
+// advanced past their deadline.
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+func (clock *fakeClock) After(d time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- clock.now
+		return ch
+	}
+
+	clock.timers = append(clock.timers, fakeTimer{at: clock.now.Add(d), ch: ch})
+	return ch
+}
+
+// Move the clock forwards, firing any timers that are now due.
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(d)
+
+	pending := clock.timers[:0]
+	for _, timer := range clock.timers {
+		if timer.at.After(clock.now) {
+			pending = append(pending, timer)
+		} else {
+			timer.ch <- timer.at
+		}
+	}
+	clock.timers = pending
+}
+
+// Wait until n timers are pending.
+func (clock *fakeClock) BlockUntil(t *testing.T, n int) {
+	require.Eventually(t, func() bool {
+		clock.mutex.Lock()
+		defer clock.mutex.Unlock()
+
+		return len(clock.timers) == n
+	}, time.Second, time.Millisecond)
+}
+
+// Start a dispatcher running in the background. Returns the channel to send
+// calls on and a channel that receives the stats when Run returns.
+func startDispatcher(
+	ctx context.Context, t *testing.T, config DispatcherConfig,
+) (*Dispatcher, chan<- *Caller, <-chan RunStats) {
+	dispatcher, err := NewDispatcher(config)
+	require.NoError(t, err)
+
+	calls := make(chan *Caller)
+	done := make(chan RunStats, 1)
+	go func() {
+		done <- dispatcher.Run(ctx, calls)
+	}()
+
+	return dispatcher, calls, done
+}
+
+// Wait until the dispatcher has recorded n finished calls.
+func waitForRecords(t *testing.T, dispatcher *Dispatcher, n int) {
+	require.Eventually(t, func() bool {
+		return len(dispatcher.Stats().Calls) == n
+	}, time.Second, time.Millisecond)
+}
+
+// Return the record for a caller.
+func findRecord(t *testing.T, stats RunStats, callerID int) CallRecord {
+	for _, record := range stats.Calls {
+		if record.CallerID == callerID {
+			return record
+		}
+	}
+
+	t.Fatalf("no record for caller %v", callerID)
+	return CallRecord{}
+}
+
+// Test that calls are queued, escalated and turned away as expected.
+func TestDispatcherQueueAndEscalation(t *testing.T) {
+	clock := newFakeClock()
+	serviceTimes := map[int]time.Duration{
+		0: 10 * time.Second,
+		1: 20 * time.Second,
+		2: 20 * time.Second,
+		3: 5 * time.Second,
+	}
+
+	dispatcher, calls, done := startDispatcher(
+		context.Background(), t, DispatcherConfig{
+			NumRespondents: 1,
+			NumManagers:    1,
+			NumDirectors:   1,
+			MaxQueueLen:    1,
+			ServiceTime: func(employee *Employee, caller *Caller) time.Duration {
+				return serviceTimes[caller.ID]
+			},
+			Clock: clock,
+		},
+	)
+
+	complaint := &Caller{ID: 0, Issue: Complaint, Patience: time.Minute}
+
+	// The first three callers take the respondent, manager and director in
+	// turn. The fourth is queued and the fifth is turned away.
+	calls <- complaint
+	clock.BlockUntil(t, 1)
+	calls <- &Caller{ID: 1, Issue: GeneralEnquiry, Patience: time.Minute}
+	clock.BlockUntil(t, 2)
+	calls <- &Caller{ID: 2, Issue: GeneralEnquiry, Patience: time.Minute}
+	clock.BlockUntil(t, 3)
+	calls <- &Caller{ID: 3, Issue: GeneralEnquiry, Patience: 30 * time.Second}
+	clock.BlockUntil(t, 4)
+	calls <- &Caller{ID: 4, Issue: GeneralEnquiry, Patience: time.Minute}
+	waitForRecords(t, dispatcher, 1)
+	close(calls)
+
+	// After 10s the respondent cannot resolve the complaint, so takes the
+	// queued caller while the complaint is escalated and queued for a
+	// manager or director.
+	clock.Advance(10 * time.Second)
+	clock.BlockUntil(t, 5)
+
+	clock.Advance(5 * time.Second)
+	waitForRecords(t, dispatcher, 2)
+
+	var stats RunStats
+	for finished := false; !finished; {
+		select {
+		case stats = <-done:
+			finished = true
+
+		case <-time.After(time.Millisecond):
+			clock.Advance(time.Second)
+		}
+	}
+
+	require.Len(t, stats.Calls, 5)
+
+	record := findRecord(t, stats, 4)
+	assert.Equal(t, TurnedAway, record.Outcome)
+
+	record = findRecord(t, stats, 3)
+	assert.Equal(t, Resolved, record.Outcome)
+	assert.Equal(t, 10*time.Second, record.Wait)
+	assert.Equal(t, 10*time.Second, record.FirstAnswer)
+	assert.Equal(t, 5*time.Second, record.Handle)
+
+	record = findRecord(t, stats, 0)
+	assert.Equal(t, Resolved, record.Outcome)
+	assert.Equal(t, time.Duration(0), record.FirstAnswer)
+	assert.GreaterOrEqual(t, record.Escalations, 1)
+	assert.GreaterOrEqual(t, record.Wait, 10*time.Second)
+
+	history := complaint.History()
+	require.GreaterOrEqual(t, len(history), 2)
+	assert.Equal(t, Respondent, history[0].Category())
+	assert.Equal(t, Director, history[len(history)-1].Category())
+
+	for _, callerID := range []int{1, 2} {
+		assert.Equal(t, Resolved, findRecord(t, stats, callerID).Outcome)
+	}
+}
+
+// Test that callers hang up once their patience runs out.
+func TestDispatcherAbandon(t *testing.T) {
+	clock := newFakeClock()
+	dispatcher, calls, done := startDispatcher(
+		context.Background(), t, DispatcherConfig{
+			NumRespondents: 1,
+			MaxQueueLen:    5,
+			ServiceTime: func(employee *Employee, caller *Caller) time.Duration {
+				return 100 * time.Second
+			},
+			Clock: clock,
+		},
+	)
+
+	calls <- &Caller{ID: 0, Issue: GeneralEnquiry, Patience: time.Minute}
+	clock.BlockUntil(t, 1)
+	calls <- &Caller{ID: 1, Issue: GeneralEnquiry, Patience: 5 * time.Second}
+	clock.BlockUntil(t, 2)
+	close(calls)
+
+	clock.Advance(5 * time.Second)
+	waitForRecords(t, dispatcher, 1)
+
+	clock.Advance(95 * time.Second)
+	stats := <-done
+
+	record := findRecord(t, stats, 1)
+	assert.Equal(t, Abandoned, record.Outcome)
+	assert.Equal(t, 5*time.Second, record.Wait)
+	assert.Equal(t, time.Duration(-1), record.FirstAnswer)
+
+	record = findRecord(t, stats, 0)
+	assert.Equal(t, Resolved, record.Outcome)
+	assert.Equal(t, 100*time.Second, record.Handle)
+
+	assert.Equal(t, 100*time.Second, stats.BusyTime[Respondent])
+	assert.Equal(t, 100*time.Second, stats.Elapsed)
+}
+
+// Test that cancelling the context stops new calls being accepted, but lets
+// calls in progress finish.
+func TestDispatcherShutdownDrains(t *testing.T) {
+	clock := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var events []CallEventKind
+	_, calls, done := startDispatcher(ctx, t, DispatcherConfig{
+		NumRespondents: 1,
+		ServiceTime: func(employee *Employee, caller *Caller) time.Duration {
+			return time.Minute
+		},
+		Clock:   clock,
+		OnEvent: func(event CallEvent) { events = append(events, event.Kind) },
+	})
+
+	calls <- &Caller{ID: 0, Issue: GeneralEnquiry, Patience: time.Minute}
+	clock.BlockUntil(t, 1)
+	cancel()
+
+	// Nobody is reading calls any more.
+	select {
+	case calls <- &Caller{ID: 1}:
+		t.Error("call accepted after shutdown")
+
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	select {
+	case <-done:
+		t.Fatal("Run returned before in-flight call finished")
+
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	stats := <-done
+
+	require.Len(t, stats.Calls, 1)
+	assert.Equal(t, Resolved, stats.Calls[0].Outcome)
+	assert.Equal(
+		t, []CallEventKind{CallArrived, CallAnswered, CallResolved}, events,
+	)
+}
+
+// Test that callers wait for an employee with the skills they need, even when
+// other employees are free.
+func TestDispatcherSkills(t *testing.T) {
+	clock := newFakeClock()
+	_, calls, done := startDispatcher(
+		context.Background(), t, DispatcherConfig{
+			NumRespondents: 2,
+			MaxQueueLen:    5,
+			StaffSkills: [3][][]Skill{
+				Respondent: {{"lang:fr"}},
+			},
+			ServiceTime: func(employee *Employee, caller *Caller) time.Duration {
+				return 10 * time.Second
+			},
+			Clock: clock,
+		},
+	)
+
+	french := &Caller{
+		ID:       0,
+		Issue:    GeneralEnquiry,
+		Patience: time.Minute,
+		Skills:   []Skill{"lang:fr"},
+	}
+	calls <- french
+	clock.BlockUntil(t, 1)
+
+	secondFrench := &Caller{
+		ID:       1,
+		Issue:    GeneralEnquiry,
+		Patience: time.Minute,
+		Skills:   []Skill{"lang:fr"},
+	}
+	calls <- secondFrench
+	clock.BlockUntil(t, 2)
+
+	// Respondent #1 is free but does not speak French, so takes this call
+	// instead.
+	english := &Caller{ID: 2, Issue: GeneralEnquiry, Patience: time.Minute}
+	calls <- english
+	clock.BlockUntil(t, 3)
+	close(calls)
+
+	clock.Advance(10 * time.Second)
+	clock.BlockUntil(t, 2)
+	clock.Advance(10 * time.Second)
+	stats := <-done
+
+	assert.Equal(t, 0, french.History()[0].ID())
+	assert.Equal(t, 0, secondFrench.History()[0].ID())
+	assert.Equal(t, 1, english.History()[0].ID())
+
+	record := findRecord(t, stats, 1)
+	assert.Equal(t, Resolved, record.Outcome)
+	assert.Equal(t, 10*time.Second, record.Wait)
+	assert.Equal(t, time.Duration(0), findRecord(t, stats, 2).Wait)
+}
+
+// Test that a service time function is required.
+func TestNewDispatcherValidation(t *testing.T) {
+	_, err := NewDispatcher(DispatcherConfig{NumRespondents: 1})
+	assert.Error(t, err)
+}
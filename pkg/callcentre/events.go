@@ -0,0 +1,102 @@
+package callcentre
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Kinds of thing that can happen to a call.
+type CallEventKind int
+
+const (
+	CallArrived CallEventKind = iota
+	CallTurnedAway
+	CallQueued
+	CallAbandoned
+	CallAnswered
+	CallEscalated
+	CallResolved
+)
+
+// Get the event kind as a string.
+func (kind CallEventKind) String() string {
+	switch kind {
+	case CallArrived:
+		return "arrived"
+
+	case CallTurnedAway:
+		return "turned_away"
+
+	case CallQueued:
+		return "queued"
+
+	case CallAbandoned:
+		return "abandoned"
+
+	case CallAnswered:
+		return "answered"
+
+	case CallEscalated:
+		return "escalated"
+
+	case CallResolved:
+		return "resolved"
+
+	default:
+		panic("Unexpected call event kind")
+	}
+}
+
+// Something that happened to a call during a run.
+type CallEvent struct {
+	Time     time.Duration // time since the start of the run
+	CallerID int
+	Kind     CallEventKind
+
+	// The employee involved, for answered, escalated and resolved events.
+	Employee *Employee
+}
+
+// Writes call events as CSV, one row per event.
+type EventLogWriter struct {
+	writer *csv.Writer
+}
+
+// Initialise a new event log writer. The header row is written straight away.
+func NewEventLogWriter(w io.Writer) (*EventLogWriter, error) {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"time_s", "caller_id", "event", "category", "employee_id",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventLogWriter{writer: writer}, nil
+}
+
+// Write a row for an event. Errors are reported by Flush.
+func (log *EventLogWriter) Record(event CallEvent) {
+	row := []string{
+		strconv.FormatFloat(event.Time.Seconds(), 'f', 3, 64),
+		strconv.Itoa(event.CallerID),
+		event.Kind.String(),
+		"",
+		"",
+	}
+
+	if event.Employee != nil {
+		row[3] = event.Employee.category.String()
+		row[4] = strconv.Itoa(event.Employee.id)
+	}
+
+	log.writer.Write(row)
+}
+
+// Flush any buffered rows, returning the first error from writing any row.
+func (log *EventLogWriter) Flush() error {
+	log.writer.Flush()
+	return log.writer.Error()
+}
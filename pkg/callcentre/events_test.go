@@ -0,0 +1,33 @@
+package callcentre
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := NewEventLogWriter(&buf)
+	require.NoError(t, err)
+
+	log.Record(CallEvent{Time: 0, CallerID: 3, Kind: CallArrived})
+	log.Record(CallEvent{
+		Time:     1500 * time.Millisecond,
+		CallerID: 3,
+		Kind:     CallAnswered,
+		Employee: InitEmployee(2, Manager),
+	})
+	require.NoError(t, log.Flush())
+
+	assert.Equal(
+		t,
+		"time_s,caller_id,event,category,employee_id\n"+
+			"0.000,3,arrived,,\n"+
+			"1.500,3,answered,Manager,2\n",
+		buf.String(),
+	)
+}
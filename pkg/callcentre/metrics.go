@@ -0,0 +1,223 @@
+package callcentre
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// How a call ended.
+type CallOutcome int
+
+const (
+	Pending CallOutcome = iota
+	Resolved
+	Abandoned
+	TurnedAway
+)
+
+// Measurements for a single call.
+type CallRecord struct {
+	CallerID    int
+	Issue       IssueType
+	Arrival     time.Duration // since the start of the run
+	Wait        time.Duration // total time spent in the queue
+	LongestWait time.Duration // longest single spell in the queue
+	FirstAnswer time.Duration // time until first answered, -1 if never answered
+	Handle      time.Duration // total time spent talking to employees
+	Escalations int
+	Outcome     CallOutcome
+}
+
+// Record a spell of waiting in the queue.
+func (record *CallRecord) addWait(wait time.Duration) {
+	record.Wait += wait
+	if wait > record.LongestWait {
+		record.LongestWait = wait
+	}
+}
+
+// Raw measurements collected over a run of the call centre, whether real or
+// simulated.
+type RunStats struct {
+	Calls    []CallRecord
+	Staff    [3]int           // number of employees, per EmployeeCategory
+	BusyTime [3]time.Duration // total time spent on calls, per EmployeeCategory
+	Elapsed  time.Duration    // length of the run
+}
+
+// Summary of a run, used to compare staffing plans.
+type Report struct {
+	Calls      int `json:"calls"`
+	Resolved   int `json:"resolved"`
+	Abandoned  int `json:"abandoned"`
+	TurnedAway int `json:"turned_away"`
+
+	// Wait statistics cover every call that was admitted to the call centre,
+	// i.e. all calls except those turned away, in seconds.
+	AvgWait float64 `json:"avg_wait_s"`
+	P50Wait float64 `json:"p50_wait_s"`
+	P90Wait float64 `json:"p90_wait_s"`
+	P99Wait float64 `json:"p99_wait_s"`
+	MaxWait float64 `json:"max_wait_s"`
+
+	AvgHandle      float64 `json:"avg_handle_s"`
+	AvgEscalations float64 `json:"avg_escalations"`
+
+	// The fraction of all calls that were answered within the threshold.
+	// Abandoned and turned away calls count as missing the target.
+	ServiceLevel          float64 `json:"service_level"`
+	ServiceLevelThreshold float64 `json:"service_level_threshold_s"`
+
+	// The fraction of available time that employees in each category spent
+	// handling calls.
+	Occupancy map[string]float64 `json:"occupancy"`
+}
+
+// Summarise the stats from a run. Calls answered within threshold count
+// towards the service level.
+func BuildReport(stats RunStats, threshold time.Duration) Report {
+	report := Report{
+		Calls:                 len(stats.Calls),
+		ServiceLevelThreshold: threshold.Seconds(),
+		Occupancy:             make(map[string]float64),
+	}
+
+	var waits []time.Duration
+	var totalWait, totalHandle time.Duration
+	totalEscalations := 0
+	withinTarget := 0
+
+	for _, call := range stats.Calls {
+		switch call.Outcome {
+		case Resolved:
+			report.Resolved++
+
+		case Abandoned:
+			report.Abandoned++
+
+		case TurnedAway:
+			report.TurnedAway++
+			continue
+		}
+
+		waits = append(waits, call.Wait)
+		totalWait += call.Wait
+		totalHandle += call.Handle
+		totalEscalations += call.Escalations
+
+		if call.FirstAnswer >= 0 && call.FirstAnswer <= threshold {
+			withinTarget++
+		}
+	}
+
+	if len(waits) > 0 {
+		sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+		report.AvgWait = totalWait.Seconds() / float64(len(waits))
+		report.P50Wait = percentile(waits, 0.5).Seconds()
+		report.P90Wait = percentile(waits, 0.9).Seconds()
+		report.P99Wait = percentile(waits, 0.99).Seconds()
+		report.MaxWait = waits[len(waits)-1].Seconds()
+		report.AvgHandle = totalHandle.Seconds() / float64(len(waits))
+		report.AvgEscalations = float64(totalEscalations) / float64(len(waits))
+	}
+
+	if len(stats.Calls) > 0 {
+		report.ServiceLevel = float64(withinTarget) / float64(len(stats.Calls))
+	}
+
+	for category := range stats.Staff {
+		available := time.Duration(stats.Staff[category]) * stats.Elapsed
+		occupancy := 0.0
+		if available > 0 {
+			occupancy = float64(stats.BusyTime[category]) / float64(available)
+		}
+		report.Occupancy[EmployeeCategory(category).String()] = occupancy
+	}
+
+	return report
+}
+
+// Return the value at quantile q of a sorted slice, using the nearest-rank
+// method.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := int(q*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+
+	return sorted[rank]
+}
+
+// Write the report as human-readable text.
+func (report Report) WriteText(w io.Writer) error {
+	seconds := func(s float64) time.Duration {
+		return time.Duration(s * float64(time.Second)).Round(time.Second / 10)
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"Calls:           %v\n"+
+			"Resolved:        %v\n"+
+			"Abandoned:       %v\n"+
+			"Turned away:     %v\n"+
+			"Wait avg/p50/p90/p99/max: %v / %v / %v / %v / %v\n"+
+			"Avg handle time: %v\n"+
+			"Avg escalations: %.2f\n"+
+			"Service level:   %.1f%% answered within %v\n",
+		report.Calls,
+		report.Resolved,
+		report.Abandoned,
+		report.TurnedAway,
+		seconds(report.AvgWait),
+		seconds(report.P50Wait),
+		seconds(report.P90Wait),
+		seconds(report.P99Wait),
+		seconds(report.MaxWait),
+		seconds(report.AvgHandle),
+		report.AvgEscalations,
+		report.ServiceLevel*100,
+		seconds(report.ServiceLevelThreshold),
+	)
+	if err != nil {
+		return err
+	}
+
+	for category := Respondent; category <= Director; category++ {
+		name := category.String()
+		_, err = fmt.Fprintf(
+			w, "Occupancy (%v): %.1f%%\n", name, report.Occupancy[name]*100,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write the report as indented JSON.
+func (report Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// Write the report in the given format: "text" or "json".
+func (report Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		return report.WriteText(w)
+
+	case "json":
+		return report.WriteJSON(w)
+
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
@@ -0,0 +1,86 @@
+package callcentre
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Second
+	}
+
+	assert.Equal(t, time.Second, percentile(sorted, 0))
+	assert.Equal(t, 50*time.Second, percentile(sorted, 0.5))
+	assert.Equal(t, 90*time.Second, percentile(sorted, 0.9))
+	assert.Equal(t, 100*time.Second, percentile(sorted, 1))
+	assert.Equal(t, time.Second, percentile(sorted[:1], 0.99))
+}
+
+func TestBuildReport(t *testing.T) {
+	stats := RunStats{
+		Calls: []CallRecord{
+			{
+				CallerID:    0,
+				Outcome:     Resolved,
+				FirstAnswer: 0,
+				Handle:      2 * time.Minute,
+			},
+			{
+				CallerID:    1,
+				Outcome:     Resolved,
+				Wait:        40 * time.Second,
+				FirstAnswer: 40 * time.Second,
+				Handle:      4 * time.Minute,
+				Escalations: 1,
+			},
+			{
+				CallerID:    2,
+				Outcome:     Abandoned,
+				Wait:        time.Minute,
+				FirstAnswer: -1,
+			},
+			{CallerID: 3, Outcome: TurnedAway, FirstAnswer: -1},
+		},
+		Staff:    [3]int{2, 1, 0},
+		BusyTime: [3]time.Duration{5 * time.Minute, 1 * time.Minute},
+		Elapsed:  10 * time.Minute,
+	}
+
+	report := BuildReport(stats, 30*time.Second)
+
+	assert.Equal(t, 4, report.Calls)
+	assert.Equal(t, 2, report.Resolved)
+	assert.Equal(t, 1, report.Abandoned)
+	assert.Equal(t, 1, report.TurnedAway)
+	assert.InDelta(t, 100.0/3, report.AvgWait, 1e-9)
+	assert.Equal(t, 40.0, report.P50Wait)
+	assert.Equal(t, 60.0, report.MaxWait)
+	assert.InDelta(t, 120.0, report.AvgHandle, 1e-9)
+	assert.InDelta(t, 1.0/3, report.AvgEscalations, 1e-9)
+	assert.Equal(t, 0.25, report.ServiceLevel)
+	assert.Equal(t, map[string]float64{
+		"Respondent": 0.25,
+		"Manager":    0.1,
+		"Director":   0,
+	}, report.Occupancy)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, "json"))
+
+	var decoded Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, report, decoded)
+
+	buf.Reset()
+	require.NoError(t, report.Write(&buf, "text"))
+	assert.Contains(t, buf.String(), "Respondent")
+
+	assert.Error(t, report.Write(&buf, "xml"))
+}
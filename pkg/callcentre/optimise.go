@@ -0,0 +1,319 @@
+package callcentre
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Inputs to the staffing optimiser. The Sim config supplies the arrival rate,
+// handle time distributions and patience; its staff counts and event callback
+// are ignored.
+type OptimiseConfig struct {
+	Sim        SimConfig
+	Target     float64       // required fraction of calls answered in time
+	Threshold  time.Duration // answer time that counts towards the target
+	HourlyCost [3]float64    // cost per employee per hour, per category
+	Method     string        // "erlang" or "sim"
+}
+
+// A staffing plan and how it performs.
+type StaffingPlan struct {
+	Staff        [3]int
+	HourlyCost   float64
+	ServiceLevel float64
+}
+
+// The most employees of any one category the optimiser will consider.
+const maxStaff = 1000
+
+// Find the cheapest staffing plan that meets the service level target.
+func Optimise(config OptimiseConfig) (StaffingPlan, error) {
+	if err := config.validate(); err != nil {
+		return StaffingPlan{}, err
+	}
+
+	estimate, err := erlangStaffing(config)
+	if err != nil {
+		return StaffingPlan{}, err
+	}
+
+	switch config.Method {
+	case "erlang":
+		return estimate, nil
+
+	case "sim":
+		return simStaffing(config, estimate.Staff)
+
+	default:
+		return StaffingPlan{}, fmt.Errorf("unknown method %q", config.Method)
+	}
+}
+
+// Check that an optimiser config makes sense.
+func (config OptimiseConfig) validate() error {
+	if config.Target <= 0 || config.Target >= 1 {
+		return errors.New("target must be between 0 and 1")
+	}
+
+	if config.Sim.ArrivalRate <= 0 {
+		return errors.New("arrival rate must be >0")
+	}
+
+	for category := range config.HourlyCost {
+		if config.HourlyCost[category] <= 0 {
+			return fmt.Errorf(
+				"%v hourly cost must be >0", EmployeeCategory(category),
+			)
+		}
+
+		if config.Sim.ServiceTimes[category] == nil &&
+			EscalationFraction(EmployeeCategory(category)) > 0 {
+			return fmt.Errorf(
+				"no service time distribution for %v", EmployeeCategory(category),
+			)
+		}
+	}
+
+	return nil
+}
+
+// Size each tier independently with the Erlang C formula. Every call is first
+// answered by a respondent, and a fixed fraction of calls are escalated to
+// each higher tier, so each tier sees a Poisson stream of calls at a known
+// rate. Each tier is given the fewest employees that meet the target on its
+// own; since costs add up across tiers this is also the cheapest plan. The
+// model ignores abandonment and overflow between tiers, so treat it as an
+// estimate.
+func erlangStaffing(config OptimiseConfig) (StaffingPlan, error) {
+	var plan StaffingPlan
+	plan.ServiceLevel = 1
+
+	for category := range plan.Staff {
+		callsPerHour := config.Sim.ArrivalRate *
+			EscalationFraction(EmployeeCategory(category))
+		if callsPerHour == 0 {
+			// No calls reach this tier, so it may have no distribution.
+			continue
+		}
+
+		handleTime := config.Sim.ServiceTimes[category].Mean()
+
+		staff, level, err := erlangAgents(
+			callsPerHour, handleTime, config.Threshold, config.Target,
+		)
+		if err != nil {
+			return StaffingPlan{}, fmt.Errorf(
+				"%v: %v", EmployeeCategory(category), err,
+			)
+		}
+
+		plan.Staff[category] = staff
+		plan.ServiceLevel = math.Min(plan.ServiceLevel, level)
+	}
+
+	plan.HourlyCost = staffingCost(plan.Staff, config.HourlyCost)
+	return plan, nil
+}
+
+// Return the fewest agents that answer the target fraction of calls within
+// the threshold, according to Erlang C, along with the service level they
+// achieve.
+func erlangAgents(
+	callsPerHour float64, handleTime, threshold time.Duration, target float64,
+) (int, float64, error) {
+	if callsPerHour == 0 {
+		return 0, 1, nil
+	}
+
+	load := callsPerHour * handleTime.Hours() // offered traffic in erlangs
+
+	for agents := int(math.Floor(load)) + 1; agents <= maxStaff; agents++ {
+		level := erlangServiceLevel(agents, load, handleTime, threshold)
+		if level >= target {
+			return agents, level, nil
+		}
+	}
+
+	return 0, 0, errors.New("target cannot be met")
+}
+
+// Return the fraction of calls answered within the threshold by the given
+// number of agents, under the Erlang C model. Requires agents > load.
+func erlangServiceLevel(
+	agents int, load float64, handleTime, threshold time.Duration,
+) float64 {
+	// Build up the Erlang B blocking probability recursively, then convert
+	// to Erlang C: the probability that a call has to wait at all.
+	blocking := 1.0
+	for n := 1; n <= agents; n++ {
+		blocking = load * blocking / (float64(n) + load*blocking)
+	}
+
+	n := float64(agents)
+	probWait := n * blocking / (n - load*(1-blocking))
+
+	return 1 - probWait*math.Exp(
+		-(n-load)*threshold.Seconds()/handleTime.Seconds(),
+	)
+}
+
+// Search for a staffing plan by simulation, starting from an initial
+// estimate. If the estimate misses the target, employees are added one at a
+// time wherever they raise the service level most per unit cost. Employees
+// are then removed one at a time, always taking the biggest saving that still
+// meets the target, until no single employee can be removed. Every candidate
+// is simulated with the same seed, so they all see the same callers.
+func simStaffing(config OptimiseConfig, estimate [3]int) (StaffingPlan, error) {
+	staff := estimate
+	for category := range staff {
+		if staff[category] < 1 {
+			staff[category] = 1
+		}
+	}
+
+	level, err := simServiceLevel(config, staff)
+	if err != nil {
+		return StaffingPlan{}, err
+	}
+
+	for level < config.Target {
+		staff, level, err = addBestEmployee(config, staff, level)
+		if err != nil {
+			return StaffingPlan{}, err
+		}
+	}
+
+	for {
+		bestSaving := 0.0
+		var next [3]int
+		var nextLevel float64
+
+		for category := range staff {
+			if staff[category] <= 1 {
+				continue
+			}
+
+			candidate := staff
+			candidate[category]--
+			saving := config.HourlyCost[category]
+			if saving <= bestSaving {
+				continue
+			}
+
+			candidateLevel, err := simServiceLevel(config, candidate)
+			if err != nil {
+				return StaffingPlan{}, err
+			}
+
+			if candidateLevel >= config.Target {
+				bestSaving = saving
+				next = candidate
+				nextLevel = candidateLevel
+			}
+		}
+
+		if bestSaving == 0 {
+			break
+		}
+
+		staff, level = next, nextLevel
+	}
+
+	return StaffingPlan{
+		Staff:        staff,
+		HourlyCost:   staffingCost(staff, config.HourlyCost),
+		ServiceLevel: level,
+	}, nil
+}
+
+// Add the employee who raises the service level most per unit cost.
+func addBestEmployee(
+	config OptimiseConfig, staff [3]int, level float64,
+) ([3]int, float64, error) {
+	bestGain := -1.0
+	var next [3]int
+	var nextLevel float64
+
+	for category := range staff {
+		if staff[category] >= maxStaff {
+			continue
+		}
+
+		candidate := staff
+		candidate[category]++
+
+		candidateLevel, err := simServiceLevel(config, candidate)
+		if err != nil {
+			return staff, level, err
+		}
+
+		gain := (candidateLevel - level) / config.HourlyCost[category]
+		if gain > bestGain {
+			bestGain = gain
+			next = candidate
+			nextLevel = candidateLevel
+		}
+	}
+
+	if bestGain < 0 {
+		return staff, level, errors.New("target cannot be met")
+	}
+
+	return next, nextLevel, nil
+}
+
+// Simulate a staffing plan and return the service level it achieves, as
+// measured in the report for a run.
+func simServiceLevel(config OptimiseConfig, staff [3]int) (float64, error) {
+	simConfig := config.Sim
+	simConfig.NumRespondents = staff[Respondent]
+	simConfig.NumManagers = staff[Manager]
+	simConfig.NumDirectors = staff[Director]
+
+	// Every candidate plan is simulated, so their events would be mixed up.
+	simConfig.OnEvent = nil
+
+	sim, err := NewSimulation(simConfig)
+	if err != nil {
+		return 0, err
+	}
+
+	stats := sim.Run()
+	if len(stats.Calls) == 0 {
+		return 1, nil
+	}
+
+	return BuildReport(stats, config.Threshold).ServiceLevel, nil
+}
+
+// Return the hourly cost of a staffing plan.
+func staffingCost(staff [3]int, hourlyCost [3]float64) float64 {
+	cost := 0.0
+	for category := range staff {
+		cost += float64(staff[category]) * hourlyCost[category]
+	}
+
+	return cost
+}
+
+// Print a staffing plan.
+func (plan StaffingPlan) Write(w io.Writer) error {
+	_, err := fmt.Fprintf(
+		w,
+		"Respondents:   %v\n"+
+			"Managers:      %v\n"+
+			"Directors:     %v\n"+
+			"Hourly cost:   %.2f\n"+
+			"Service level: %.1f%%\n",
+		plan.Staff[Respondent],
+		plan.Staff[Manager],
+		plan.Staff[Director],
+		plan.HourlyCost,
+		plan.ServiceLevel*100,
+	)
+
+	return err
+}
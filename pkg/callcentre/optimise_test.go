@@ -0,0 +1,128 @@
+package callcentre
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check the Erlang C formula against a textbook value: with 10 erlangs of
+// traffic and 11 agents, 68.2% of calls have to wait.
+func TestErlangServiceLevel(t *testing.T) {
+	level := erlangServiceLevel(11, 10, time.Minute, 0)
+	assert.InDelta(t, 1-0.6821, level, 1e-4)
+
+	// A longer threshold can only help.
+	assert.Greater(t, erlangServiceLevel(11, 10, time.Minute, time.Minute), level)
+}
+
+func TestErlangAgents(t *testing.T) {
+	agents, level, err := erlangAgents(600, time.Minute, 0, 0.3)
+	require.NoError(t, err)
+	assert.Equal(t, 11, agents)
+	assert.GreaterOrEqual(t, level, 0.3)
+
+	agents, level, err = erlangAgents(0, time.Minute, 0, 0.8)
+	require.NoError(t, err)
+	assert.Equal(t, 0, agents)
+	assert.Equal(t, 1.0, level)
+}
+
+func testOptimiseConfig(method string) OptimiseConfig {
+	config := testSimConfig()
+	config.Patience = ConstantDist{Value: 10 * time.Minute}
+
+	return OptimiseConfig{
+		Sim:        config,
+		Target:     0.8,
+		Threshold:  20 * time.Second,
+		HourlyCost: [3]float64{20, 30, 50},
+		Method:     method,
+	}
+}
+
+func TestOptimiseErlang(t *testing.T) {
+	plan, err := Optimise(testOptimiseConfig("erlang"))
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, plan.ServiceLevel, 0.8)
+	for _, staff := range plan.Staff {
+		assert.Greater(t, staff, 0)
+	}
+	assert.Equal(t, staffingCost(plan.Staff, [3]float64{20, 30, 50}), plan.HourlyCost)
+}
+
+func TestOptimiseSim(t *testing.T) {
+	config := testOptimiseConfig("sim")
+
+	plan, err := Optimise(config)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, plan.ServiceLevel, config.Target)
+
+	level, err := simServiceLevel(config, plan.Staff)
+	require.NoError(t, err)
+	assert.Equal(t, plan.ServiceLevel, level)
+
+	// The plan's service level is the one the report for its run shows.
+	simConfig := config.Sim
+	simConfig.NumRespondents = plan.Staff[Respondent]
+	simConfig.NumManagers = plan.Staff[Manager]
+	simConfig.NumDirectors = plan.Staff[Director]
+	sim, err := NewSimulation(simConfig)
+	require.NoError(t, err)
+	report := BuildReport(sim.Run(), config.Threshold)
+	assert.Equal(t, plan.ServiceLevel, report.ServiceLevel)
+
+	// Removing any one employee should miss the target. The optimiser keeps
+	// at least one employee in each tier, so escalated calls can be resolved.
+	require.Greater(t, plan.Staff[Respondent], 1)
+	for category := range plan.Staff {
+		if plan.Staff[category] <= 1 {
+			continue
+		}
+
+		staff := plan.Staff
+		staff[category]--
+		level, err := simServiceLevel(config, staff)
+		require.NoError(t, err)
+		assert.Less(t, level, config.Target, EmployeeCategory(category).String())
+	}
+}
+
+// The optimiser simulates many candidate plans, so it must not pass their
+// events on.
+func TestOptimiseIgnoresEvents(t *testing.T) {
+	config := testOptimiseConfig("sim")
+	numEvents := 0
+	config.Sim.OnEvent = func(CallEvent) { numEvents++ }
+
+	_, err := Optimise(config)
+	require.NoError(t, err)
+	assert.Equal(t, 0, numEvents)
+}
+
+func TestOptimiseValidation(t *testing.T) {
+	config := testOptimiseConfig("erlang")
+	config.Target = 1
+	_, err := Optimise(config)
+	assert.Error(t, err)
+
+	_, err = Optimise(testOptimiseConfig("guess"))
+	assert.Error(t, err)
+
+	for _, modify := range []func(*OptimiseConfig){
+		func(c *OptimiseConfig) { c.HourlyCost[Manager] = 0 },
+		func(c *OptimiseConfig) { c.HourlyCost[Director] = -50 },
+		func(c *OptimiseConfig) { c.Sim.ArrivalRate = 0 },
+		func(c *OptimiseConfig) { c.Sim.ServiceTimes[Director] = nil },
+	} {
+		for _, method := range []string{"erlang", "sim"} {
+			config := testOptimiseConfig(method)
+			modify(&config)
+			_, err := Optimise(config)
+			assert.Error(t, err, method)
+		}
+	}
+}
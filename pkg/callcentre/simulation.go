@@ -0,0 +1,512 @@
+package callcentre
+
+import (
+	"container/heap"
+	"container/list"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Settings for a discrete-event simulation of the call centre.
+type SimConfig struct {
+	NumRespondents int
+	NumManagers    int
+	NumDirectors   int
+	MaxQueueLen    int
+
+	// Skills of each employee, by category and then ID. Employees not listed
+	// have no skills.
+	StaffSkills [3][][]Skill
+
+	// The probability that a caller needs each skill. Each skill is drawn
+	// independently.
+	CallerSkills map[Skill]float64
+
+	ArrivalRate  float64         // mean calls per hour, arriving as a Poisson process
+	Duration     time.Duration   // calls stop arriving after this much simulated time
+	ServiceTimes [3]Distribution // time to handle a call, per EmployeeCategory
+	Patience     Distribution    // how long each caller will wait in the queue
+	Seed         int64
+
+	// Recorded calls to replay. If set, these replace the random arrivals,
+	// issues and service times, and ArrivalRate and Duration are ignored.
+	Trace []TraceCall
+
+	// Called for each call event, if set.
+	OnEvent func(CallEvent)
+}
+
+// A Simulation replays the call centre rules against a virtual clock. Nothing
+// sleeps - time jumps straight to the next scheduled event - so many hours of
+// traffic can be simulated in a fraction of a second, and the same seed
+// always gives the same result.
+type Simulation struct {
+	config SimConfig
+	rng    *rand.Rand
+	now    time.Duration // virtual time since the start of the simulation
+	events eventQueue
+	seq    int // tie-breaker so simultaneous events run in scheduling order
+
+	pools        []*EmployeePool
+	callerSkills []Skill   // keys of config.CallerSkills, in a fixed order
+	queue        list.List // of *simCall, in arrival order
+	calls        []*simCall
+	busyTime     [3]time.Duration
+
+	nextCallerID int
+	nextTrace    int // index of the next call to arrive from the trace
+}
+
+// The state of a single call as it moves through the simulation.
+type simCall struct {
+	caller       *Caller
+	record       CallRecord
+	queueElem    *list.Element // non-nil while waiting in the queue
+	queuedAt     time.Duration
+	serviceStart time.Duration
+	trace        *TraceCall // the recorded call, if replaying a trace
+
+	// Sequence number of the abandon event scheduled when the call was last
+	// queued. Escalated calls can be queued more than once, so abandon events
+	// from earlier spells in the queue must be ignored.
+	abandonSeq int
+}
+
+// Kinds of event that drive the simulation.
+type eventKind int
+
+const (
+	arrivalEvent eventKind = iota
+	serviceDoneEvent
+	abandonEvent
+)
+
+type event struct {
+	at       time.Duration
+	seq      int
+	kind     eventKind
+	call     *simCall
+	employee *Employee
+}
+
+// Events are processed in time order, using a min-heap.
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	ev := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return ev
+}
+
+// Initialise a new simulation.
+func NewSimulation(config SimConfig) (*Simulation, error) {
+	if config.Trace == nil && config.ArrivalRate <= 0 {
+		return nil, errors.New("arrival rate must be >0")
+	}
+
+	for i := range config.ServiceTimes {
+		if config.Trace == nil && config.ServiceTimes[i] == nil {
+			return nil, fmt.Errorf(
+				"no service time distribution for %v",
+				EmployeeCategory(i),
+			)
+		}
+	}
+
+	if config.Patience == nil {
+		return nil, errors.New("no patience distribution")
+	}
+
+	for i := 1; i < len(config.Trace); i++ {
+		if config.Trace[i].Arrival < config.Trace[i-1].Arrival {
+			return nil, errors.New("trace is not in arrival order")
+		}
+	}
+
+	callerSkills := make([]Skill, 0, len(config.CallerSkills))
+	for skill, prob := range config.CallerSkills {
+		if prob < 0 || prob > 1 {
+			return nil, fmt.Errorf("invalid probability for skill %v", skill)
+		}
+		callerSkills = append(callerSkills, skill)
+	}
+	sort.Slice(callerSkills, func(i, j int) bool {
+		return callerSkills[i] < callerSkills[j]
+	})
+
+	return &Simulation{
+		config: config,
+		rng:    rand.New(rand.NewSource(config.Seed)),
+		pools: initPools([3]int{
+			config.NumRespondents, config.NumManagers, config.NumDirectors,
+		}, config.StaffSkills),
+		callerSkills: callerSkills,
+	}, nil
+}
+
+// Run the simulation until every call has finished. Returns the stats for
+// every call, in arrival order.
+func (sim *Simulation) Run() RunStats {
+	sim.scheduleNextArrival()
+
+	for sim.events.Len() > 0 {
+		ev := heap.Pop(&sim.events).(*event)
+		sim.now = ev.at
+
+		switch ev.kind {
+		case arrivalEvent:
+			sim.handleArrival()
+
+		case serviceDoneEvent:
+			sim.handleServiceDone(ev.call, ev.employee)
+
+		case abandonEvent:
+			sim.handleAbandon(ev)
+
+		default:
+			panic("Unexpected event kind")
+		}
+	}
+
+	stats := RunStats{
+		Calls:    make([]CallRecord, len(sim.calls)),
+		BusyTime: sim.busyTime,
+		Elapsed:  sim.now,
+	}
+
+	for i, call := range sim.calls {
+		stats.Calls[i] = call.record
+	}
+
+	stats.Staff = [3]int{
+		sim.config.NumRespondents, sim.config.NumManagers, sim.config.NumDirectors,
+	}
+
+	if sim.config.Trace == nil && stats.Elapsed < sim.config.Duration {
+		stats.Elapsed = sim.config.Duration
+	}
+
+	return stats
+}
+
+// Schedule an event at a time relative to now.
+func (sim *Simulation) schedule(
+	delay time.Duration, kind eventKind, call *simCall, employee *Employee,
+) {
+	heap.Push(&sim.events, &event{
+		at:       sim.now + delay,
+		seq:      sim.seq,
+		kind:     kind,
+		call:     call,
+		employee: employee,
+	})
+	sim.seq++
+}
+
+// Schedule the next arrival. When replaying a trace this is the next call in
+// the trace, otherwise gaps between Poisson arrivals are exponentially
+// distributed.
+func (sim *Simulation) scheduleNextArrival() {
+	if sim.config.Trace != nil {
+		if sim.nextTrace < len(sim.config.Trace) {
+			gap := sim.config.Trace[sim.nextTrace].Arrival - sim.now
+			sim.schedule(gap, arrivalEvent, nil, nil)
+		}
+		return
+	}
+
+	meanGap := float64(time.Hour) / sim.config.ArrivalRate
+	gap := time.Duration(sim.rng.ExpFloat64() * meanGap)
+
+	if sim.now+gap < sim.config.Duration {
+		sim.schedule(gap, arrivalEvent, nil, nil)
+	}
+}
+
+// A new caller rings in.
+func (sim *Simulation) handleArrival() {
+	call := &simCall{}
+	caller := &Caller{}
+
+	if sim.config.Trace != nil {
+		call.trace = &sim.config.Trace[sim.nextTrace]
+		caller.ID = call.trace.CallerID
+		caller.Issue = call.trace.Issue
+		sim.nextTrace++
+	} else {
+		caller.ID = sim.nextCallerID
+		caller.Issue = RandomIssue(sim.rng)
+		sim.nextCallerID++
+	}
+
+	caller.Patience = sim.config.Patience.Sample(sim.rng)
+	caller.Skills = sim.randomSkills()
+	call.caller = caller
+
+	call.record = CallRecord{
+		CallerID:    caller.ID,
+		Issue:       caller.Issue,
+		Arrival:     sim.now,
+		FirstAnswer: -1,
+	}
+	sim.calls = append(sim.calls, call)
+	sim.event(CallArrived, call, nil)
+
+	sim.assignOrQueue(call)
+	sim.scheduleNextArrival()
+}
+
+// Pick the skills needed by a new caller.
+func (sim *Simulation) randomSkills() []Skill {
+	var skills []Skill
+	for _, skill := range sim.callerSkills {
+		if sim.rng.Float64() < sim.config.CallerSkills[skill] {
+			skills = append(skills, skill)
+		}
+	}
+
+	return skills
+}
+
+// An employee finishes handling a call. The call is either resolved or
+// escalated, and the employee moves on to the next eligible caller.
+func (sim *Simulation) handleServiceDone(call *simCall, employee *Employee) {
+	handleTime := sim.now - call.serviceStart
+	call.record.Handle += handleTime
+	sim.busyTime[employee.category] += handleTime
+
+	if employee.resolves(call.caller) {
+		sim.event(CallResolved, call, employee)
+		call.record.Outcome = Resolved
+	} else {
+		sim.event(CallEscalated, call, employee)
+		call.caller.escalate(employee)
+		call.record.Escalations++
+		sim.assignOrQueue(call)
+	}
+
+	sim.release(employee)
+}
+
+// A caller gives up waiting. Callers who were assigned an employee before
+// their patience ran out have already left the queue, so the event is stale.
+func (sim *Simulation) handleAbandon(ev *event) {
+	call := ev.call
+	if call.queueElem == nil || call.abandonSeq != ev.seq {
+		return
+	}
+
+	sim.dequeue(call)
+	sim.event(CallAbandoned, call, nil)
+	call.record.Outcome = Abandoned
+}
+
+// Assign a free employee to a call, or queue it if nobody eligible is free.
+// New callers are turned away if the queue is full; escalated calls are
+// always queued.
+func (sim *Simulation) assignOrQueue(call *simCall) {
+	employee, ok := acquireEmployee(sim.pools, call.caller)
+	if ok {
+		sim.startService(call, employee)
+		return
+	}
+
+	if len(call.caller.history) == 0 &&
+		sim.queue.Len() >= sim.config.MaxQueueLen {
+		sim.event(CallTurnedAway, call, nil)
+		call.record.Outcome = TurnedAway
+		return
+	}
+
+	sim.event(CallQueued, call, nil)
+	call.queueElem = sim.queue.PushBack(call)
+	call.queuedAt = sim.now
+	call.abandonSeq = sim.seq
+	sim.schedule(call.caller.Patience, abandonEvent, call, nil)
+}
+
+// Remove a call from the queue, recording how long it waited.
+func (sim *Simulation) dequeue(call *simCall) {
+	sim.queue.Remove(call.queueElem)
+	call.queueElem = nil
+	call.record.addWait(sim.now - call.queuedAt)
+}
+
+// Start an employee handling a call.
+func (sim *Simulation) startService(call *simCall, employee *Employee) {
+	if call.record.FirstAnswer < 0 {
+		call.record.FirstAnswer = sim.now - call.record.Arrival
+	}
+	call.serviceStart = sim.now
+	call.caller.history = append(call.caller.history, employee)
+	sim.event(CallAnswered, call, employee)
+
+	var serviceTime time.Duration
+	if call.trace != nil {
+		serviceTime = call.trace.Service
+	} else {
+		serviceTime = sim.config.ServiceTimes[employee.category].Sample(sim.rng)
+	}
+	sim.schedule(serviceTime, serviceDoneEvent, call, employee)
+}
+
+// Report a call event to the OnEvent callback.
+func (sim *Simulation) event(
+	kind CallEventKind, call *simCall, employee *Employee,
+) {
+	if sim.config.OnEvent == nil {
+		return
+	}
+
+	sim.config.OnEvent(CallEvent{
+		Time:     sim.now,
+		CallerID: call.caller.ID,
+		Kind:     kind,
+		Employee: employee,
+	})
+}
+
+// Hand a released employee to the longest-waiting eligible caller, or
+// return them to their pool.
+func (sim *Simulation) release(employee *Employee) {
+	for el := sim.queue.Front(); el != nil; el = el.Next() {
+		call := el.Value.(*simCall)
+		if canHandle(employee, call.caller) {
+			sim.dequeue(call)
+			sim.startService(call, employee)
+			return
+		}
+	}
+
+	sim.pools[employee.category].release(employee)
+}
+
+// A Distribution produces random durations, e.g. for service times.
+type Distribution interface {
+	Sample(rng *rand.Rand) time.Duration
+	Mean() time.Duration
+}
+
+// Always returns the same duration.
+type ConstantDist struct {
+	Value time.Duration
+}
+
+func (dist ConstantDist) Sample(rng *rand.Rand) time.Duration {
+	return dist.Value
+}
+
+func (dist ConstantDist) Mean() time.Duration {
+	return dist.Value
+}
+
+// Exponentially distributed durations with the given mean.
+type ExponentialDist struct {
+	MeanValue time.Duration
+}
+
+func (dist ExponentialDist) Sample(rng *rand.Rand) time.Duration {
+	return time.Duration(rng.ExpFloat64() * float64(dist.MeanValue))
+}
+
+func (dist ExponentialDist) Mean() time.Duration {
+	return dist.MeanValue
+}
+
+// Durations distributed uniformly between Min and Max.
+type UniformDist struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (dist UniformDist) Sample(rng *rand.Rand) time.Duration {
+	return dist.Min + time.Duration(rng.Int63n(int64(dist.Max-dist.Min)+1))
+}
+
+func (dist UniformDist) Mean() time.Duration {
+	return (dist.Min + dist.Max) / 2
+}
+
+// Parse a distribution from a string of the form "const:5m", "exp:5m" or
+// "uniform:2m-8m".
+func ParseDistribution(spec string) (Distribution, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid distribution %q", spec)
+	}
+
+	switch parts[0] {
+	case "const":
+		value, err := parseNonNegativeDuration(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		return ConstantDist{Value: value}, nil
+
+	case "exp":
+		mean, err := parseNonNegativeDuration(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		return ExponentialDist{MeanValue: mean}, nil
+
+	case "uniform":
+		bounds := strings.SplitN(parts[1], "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid uniform distribution %q", spec)
+		}
+
+		min, err := parseNonNegativeDuration(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+
+		max, err := parseNonNegativeDuration(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if max < min {
+			return nil, fmt.Errorf("invalid uniform distribution %q", spec)
+		}
+		return UniformDist{Min: min, Max: max}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown distribution type %q", parts[0])
+	}
+}
+
+// Parse a duration for a distribution, which can't be negative: events
+// scheduled in the past would run the virtual clock backwards.
+func parseNonNegativeDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("duration %v must not be negative", d)
+	}
+
+	return d, nil
+}
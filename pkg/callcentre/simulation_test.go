@@ -0,0 +1,241 @@
+package callcentre
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSimConfig() SimConfig {
+	return SimConfig{
+		NumRespondents: 5,
+		NumManagers:    2,
+		NumDirectors:   1,
+		MaxQueueLen:    10,
+		ArrivalRate:    60,
+		Duration:       4 * time.Hour,
+		ServiceTimes: [3]Distribution{
+			ExponentialDist{MeanValue: 4 * time.Minute},
+			ExponentialDist{MeanValue: 6 * time.Minute},
+			ExponentialDist{MeanValue: 8 * time.Minute},
+		},
+		Patience: ExponentialDist{MeanValue: 3 * time.Minute},
+		Seed:     42,
+	}
+}
+
+// Test that the same seed gives the same results.
+func TestSimulationDeterministic(t *testing.T) {
+	first, err := NewSimulation(testSimConfig())
+	require.NoError(t, err)
+
+	second, err := NewSimulation(testSimConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, first.Run(), second.Run())
+}
+
+// Test that every call finishes with a consistent record.
+func TestSimulationRecords(t *testing.T) {
+	sim, err := NewSimulation(testSimConfig())
+	require.NoError(t, err)
+
+	stats := sim.Run()
+	require.NotEmpty(t, stats.Calls)
+
+	for i, call := range stats.Calls {
+		assert.Equal(t, i, call.CallerID)
+		assert.NotEqual(t, Pending, call.Outcome)
+		assert.LessOrEqual(t, call.Arrival, testSimConfig().Duration)
+		assert.LessOrEqual(t, call.LongestWait, call.Wait)
+
+		switch call.Outcome {
+		case Resolved:
+			assert.GreaterOrEqual(t, call.FirstAnswer, time.Duration(0))
+			assert.Greater(t, call.Handle, time.Duration(0))
+
+		case TurnedAway:
+			assert.Equal(t, time.Duration(0), call.Wait)
+			assert.Equal(t, 0, call.Escalations)
+		}
+	}
+
+	for category := range stats.BusyTime {
+		assert.LessOrEqual(
+			t,
+			stats.BusyTime[category],
+			time.Duration(stats.Staff[category])*stats.Elapsed,
+		)
+	}
+}
+
+// Test that every call is resolved when callers never hang up, escalating at
+// most as far as the issue requires. Calls may skip tiers when the junior
+// tiers are all busy.
+func TestSimulationEscalation(t *testing.T) {
+	config := testSimConfig()
+	config.Patience = ConstantDist{Value: 24 * time.Hour}
+	config.MaxQueueLen = 1000
+
+	sim, err := NewSimulation(config)
+	require.NoError(t, err)
+
+	for _, call := range sim.Run().Calls {
+		require.Equal(t, Resolved, call.Outcome)
+		assert.LessOrEqual(t, call.Escalations, int(RequiredCategory(call.Issue)))
+	}
+}
+
+// Test that callers are only handled by employees with the skills they need.
+func TestSimulationSkills(t *testing.T) {
+	config := testSimConfig()
+	config.StaffSkills = [3][][]Skill{
+		Respondent: {{"lang:fr"}, {"lang:fr"}},
+		Manager:    {{"lang:fr"}},
+		Director:   {{"lang:fr"}},
+	}
+	config.CallerSkills = map[Skill]float64{"lang:fr": 0.2}
+
+	sim, err := NewSimulation(config)
+	require.NoError(t, err)
+	sim.Run()
+
+	numFrench := 0
+	for _, call := range sim.calls {
+		if len(call.caller.Skills) == 0 {
+			continue
+		}
+
+		numFrench++
+		for _, employee := range call.caller.History() {
+			assert.True(t, employee.HasSkills(call.caller.Skills))
+		}
+	}
+	assert.Greater(t, numFrench, 0)
+}
+
+// Test replaying a trace, checking the exact sequence of events.
+func TestSimulationTrace(t *testing.T) {
+	var events []string
+	config := SimConfig{
+		NumRespondents: 1,
+		NumManagers:    1,
+		NumDirectors:   1,
+		MaxQueueLen:    1,
+		Patience:       ConstantDist{Value: time.Minute},
+		Trace: []TraceCall{
+			{Arrival: 0, CallerID: 10, Issue: GeneralEnquiry, Service: 3 * time.Minute},
+			{Arrival: time.Minute, CallerID: 11, Issue: Complaint, Service: time.Minute},
+			{Arrival: time.Minute, CallerID: 12, Issue: GeneralEnquiry, Service: time.Minute},
+			{Arrival: time.Minute, CallerID: 13, Issue: GeneralEnquiry, Service: time.Minute},
+			{Arrival: time.Minute, CallerID: 14, Issue: GeneralEnquiry, Service: time.Minute},
+		},
+		OnEvent: func(event CallEvent) {
+			description := fmt.Sprintf(
+				"%v %v %v", event.Time.Minutes(), event.CallerID, event.Kind,
+			)
+			if event.Employee != nil {
+				description += " " + event.Employee.Category().String()
+			}
+			events = append(events, description)
+		},
+	}
+
+	sim, err := NewSimulation(config)
+	require.NoError(t, err)
+	stats := sim.Run()
+
+	assert.Equal(t, []string{
+		"0 10 arrived",
+		"0 10 answered Respondent",
+		"1 11 arrived",
+		"1 11 answered Manager",
+		"1 12 arrived",
+		"1 12 answered Director",
+		"1 13 arrived",
+		"1 13 queued",
+		"1 14 arrived",
+		"1 14 turned_away",
+		// The manager cannot resolve the complaint, so it goes straight to
+		// the director rather than back to a manager.
+		"2 11 escalated Manager",
+		"2 11 queued",
+		"2 13 answered Manager",
+		"2 12 resolved Director",
+		"2 11 answered Director",
+		"3 10 resolved Respondent",
+		"3 13 resolved Manager",
+		"3 11 resolved Director",
+	}, events)
+
+	assert.Equal(t, 3*time.Minute, stats.Elapsed)
+	assert.Equal(t, []int{10, 11, 12, 13, 14}, callerIDs(stats))
+}
+
+func callerIDs(stats RunStats) []int {
+	var ids []int
+	for _, call := range stats.Calls {
+		ids = append(ids, call.CallerID)
+	}
+
+	return ids
+}
+
+func TestNewSimulationValidation(t *testing.T) {
+	config := testSimConfig()
+	config.ArrivalRate = 0
+	_, err := NewSimulation(config)
+	assert.Error(t, err)
+
+	config = testSimConfig()
+	config.ServiceTimes[Manager] = nil
+	_, err = NewSimulation(config)
+	assert.Error(t, err)
+
+	config = testSimConfig()
+	config.CallerSkills = map[Skill]float64{"lang:fr": 1.5}
+	_, err = NewSimulation(config)
+	assert.Error(t, err)
+
+	config = testSimConfig()
+	config.Trace = []TraceCall{{Arrival: time.Minute}, {Arrival: 0}}
+	_, err = NewSimulation(config)
+	assert.Error(t, err)
+
+	config = testSimConfig()
+	config.Patience = nil
+	_, err = NewSimulation(config)
+	assert.Error(t, err)
+}
+
+func TestParseDistribution(t *testing.T) {
+	dist, err := ParseDistribution("const:5m")
+	require.NoError(t, err)
+	assert.Equal(t, ConstantDist{Value: 5 * time.Minute}, dist)
+
+	dist, err = ParseDistribution("exp:90s")
+	require.NoError(t, err)
+	assert.Equal(t, ExponentialDist{MeanValue: 90 * time.Second}, dist)
+
+	dist, err = ParseDistribution("uniform:2m-8m")
+	require.NoError(t, err)
+	assert.Equal(t, UniformDist{Min: 2 * time.Minute, Max: 8 * time.Minute}, dist)
+	assert.Equal(t, 5*time.Minute, dist.Mean())
+
+	for _, spec := range []string{
+		"5m",
+		"normal:5m",
+		"exp:five",
+		"uniform:8m-2m",
+		"const:-1s",
+		"exp:-2s",
+		"uniform:-5s-1s",
+		"uniform:5s--1s",
+	} {
+		_, err = ParseDistribution(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,196 @@
+package callcentre
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A single call from a recorded trace of real traffic.
+type TraceCall struct {
+	Arrival  time.Duration // time since the start of the trace
+	CallerID int
+	Issue    IssueType
+	Service  time.Duration // time each employee spends handling the call
+}
+
+// The columns of a trace CSV file, which must start with this header row.
+var traceHeader = []string{"arrival", "caller_id", "issue", "service"}
+
+// Read a trace from CSV. Times may be given either as Go durations such as
+// "1m30s" or as a plain number of seconds, and issues either by name or by
+// number. The calls are returned in arrival order.
+func ReadTrace(r io.Reader) ([]TraceCall, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(traceHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading trace header: %v", err)
+	}
+
+	for i, column := range traceHeader {
+		if strings.ToLower(header[i]) != column {
+			return nil, fmt.Errorf(
+				"invalid trace header %v, expected %v", header, traceHeader,
+			)
+		}
+	}
+
+	var calls []TraceCall
+	callerIDs := make(map[int]bool)
+
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		call, err := parseTraceRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("trace line %v: %v", line, err)
+		}
+
+		if callerIDs[call.CallerID] {
+			return nil, fmt.Errorf(
+				"trace line %v: duplicate caller ID %v", line, call.CallerID,
+			)
+		}
+		callerIDs[call.CallerID] = true
+
+		calls = append(calls, call)
+	}
+
+	if len(calls) == 0 {
+		return nil, errors.New("trace has no calls")
+	}
+
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].Arrival < calls[j].Arrival
+	})
+
+	return calls, nil
+}
+
+// Read a trace from a CSV file.
+func ReadTraceFile(filename string) ([]TraceCall, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadTrace(f)
+}
+
+// Parse a single row of a trace.
+func parseTraceRow(row []string) (TraceCall, error) {
+	var call TraceCall
+	var err error
+
+	call.Arrival, err = parseTraceTime(row[0])
+	if err != nil {
+		return TraceCall{}, fmt.Errorf("arrival: %v", err)
+	}
+
+	call.CallerID, err = strconv.Atoi(row[1])
+	if err != nil {
+		return TraceCall{}, fmt.Errorf("caller_id: %v", err)
+	}
+
+	call.Issue, err = ParseIssueType(row[2])
+	if err != nil {
+		return TraceCall{}, err
+	}
+
+	call.Service, err = parseTraceTime(row[3])
+	if err != nil {
+		return TraceCall{}, fmt.Errorf("service: %v", err)
+	}
+
+	return call, nil
+}
+
+// Parse a time from a trace, as either a duration or a number of seconds.
+func parseTraceTime(s string) (time.Duration, error) {
+	var d time.Duration
+
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err == nil {
+		d = time.Duration(seconds * float64(time.Second))
+	} else {
+		d, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("negative time %q", s)
+	}
+
+	return d, nil
+}
+
+// Send the callers in a trace to a channel as they arrive, timed from when
+// ReplayTrace is called. Every caller has the same patience. The channel is
+// closed after the last caller, or once the context is cancelled.
+func ReplayTrace(
+	ctx context.Context, trace []TraceCall, clock Clock, patience time.Duration,
+) <-chan *Caller {
+	calls := make(chan *Caller)
+	start := clock.Now()
+
+	go func() {
+		defer close(calls)
+
+		for _, call := range trace {
+			select {
+			case <-clock.After(call.Arrival - clock.Now().Sub(start)):
+			case <-ctx.Done():
+				return
+			}
+
+			caller := &Caller{
+				ID:       call.CallerID,
+				Issue:    call.Issue,
+				Patience: patience,
+			}
+
+			select {
+			case calls <- caller:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return calls
+}
+
+// Return a service time function for a Dispatcher that gives each caller in
+// the trace their recorded service time. Callers not in the trace take no
+// time at all.
+func TraceServiceTime(
+	trace []TraceCall,
+) func(employee *Employee, caller *Caller) time.Duration {
+	serviceTimes := make(map[int]time.Duration, len(trace))
+	for _, call := range trace {
+		serviceTimes[call.CallerID] = call.Service
+	}
+
+	return func(employee *Employee, caller *Caller) time.Duration {
+		return serviceTimes[caller.ID]
+	}
+}
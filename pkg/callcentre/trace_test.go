@@ -0,0 +1,112 @@
+package callcentre
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTrace(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader(
+		"Arrival,Caller_ID,Issue,Service\n" +
+			"0,1,general,90\n" +
+			"2m, 3, Complaint, 1m30s\n" +
+			"1.5,2,1,30s\n",
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, []TraceCall{
+		{
+			Arrival:  0,
+			CallerID: 1,
+			Issue:    GeneralEnquiry,
+			Service:  90 * time.Second,
+		},
+		{
+			Arrival:  1500 * time.Millisecond,
+			CallerID: 2,
+			Issue:    TechnicalFault,
+			Service:  30 * time.Second,
+		},
+		{
+			Arrival:  2 * time.Minute,
+			CallerID: 3,
+			Issue:    Complaint,
+			Service:  90 * time.Second,
+		},
+	}, trace)
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"empty":        "",
+		"bad header":   "time,caller,issue,service\n0,1,general,1\n",
+		"no calls":     "arrival,caller_id,issue,service\n",
+		"short row":    "arrival,caller_id,issue,service\n0,1,general\n",
+		"bad arrival":  "arrival,caller_id,issue,service\nsoon,1,general,1\n",
+		"negative":     "arrival,caller_id,issue,service\n-1,1,general,1\n",
+		"bad caller":   "arrival,caller_id,issue,service\n0,bob,general,1\n",
+		"bad issue":    "arrival,caller_id,issue,service\n0,1,billing,1\n",
+		"bad service":  "arrival,caller_id,issue,service\n0,1,general,long\n",
+		"duplicate ID": "arrival,caller_id,issue,service\n0,1,general,1\n1,1,general,1\n",
+	} {
+		_, err := ReadTrace(strings.NewReader(input))
+		assert.Error(t, err, name)
+	}
+}
+
+// Test that callers are sent at their arrival times.
+func TestReplayTrace(t *testing.T) {
+	clock := newFakeClock()
+	trace := []TraceCall{
+		{Arrival: 0, CallerID: 7, Issue: Complaint},
+		{Arrival: 10 * time.Second, CallerID: 8, Issue: GeneralEnquiry},
+	}
+
+	calls := ReplayTrace(context.Background(), trace, clock, time.Minute)
+
+	caller := <-calls
+	assert.Equal(t, &Caller{ID: 7, Issue: Complaint, Patience: time.Minute}, caller)
+
+	clock.BlockUntil(t, 1)
+	select {
+	case <-calls:
+		t.Fatal("caller sent before arrival time")
+	default:
+	}
+
+	clock.Advance(10 * time.Second)
+	caller = <-calls
+	assert.Equal(t, 8, caller.ID)
+
+	_, ok := <-calls
+	assert.False(t, ok)
+}
+
+func TestReplayTraceCancel(t *testing.T) {
+	clock := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := ReplayTrace(ctx, []TraceCall{{Arrival: time.Hour}}, clock, time.Minute)
+	clock.BlockUntil(t, 1)
+	cancel()
+
+	_, ok := <-calls
+	assert.False(t, ok)
+}
+
+func TestTraceServiceTime(t *testing.T) {
+	serviceTime := TraceServiceTime([]TraceCall{
+		{CallerID: 1, Service: time.Minute},
+		{CallerID: 2, Service: time.Second},
+	})
+
+	employee := InitEmployee(0, Respondent)
+	assert.Equal(t, time.Minute, serviceTime(employee, &Caller{ID: 1}))
+	assert.Equal(t, time.Second, serviceTime(employee, &Caller{ID: 2}))
+	assert.Equal(t, time.Duration(0), serviceTime(employee, &Caller{ID: 3}))
+}
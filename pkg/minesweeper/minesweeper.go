@@ -0,0 +1,305 @@
+package minesweeper
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/ryanc414/ctci/pkg/objects"
+)
+
+// Represents all game state. A Game does not read input or print anything -
+// callers drive it through Reveal and ToggleFlag and render it however they
+// like, which keeps it usable from tests and non-interactive frontends.
+type Game struct {
+	grid        Grid
+	status      GameStatus
+	numBombs    int
+	numExplored int
+	numMoves    int
+
+	// Time spent playing is accumulated in elapsed whenever the timer stops,
+	// i.e. when the game ends or is saved. startTime records when the timer
+	// was last (re)started. The clock may be swapped out by tests.
+	elapsed   time.Duration
+	startTime time.Time
+	clock     func() time.Time
+}
+
+// A game can either be in progress, or finished in a win or lose state.
+type GameStatus int
+
+const (
+	InProgress GameStatus = iota
+	GameWon
+	GameLost
+)
+
+type Grid [][]Cell
+
+// Each cell in the grid may be in a combination of states. We use bitflags
+// to store the states, we could have also stored a struct of bools.
+type Cell int
+
+const (
+	Bomb     Cell = 0x1
+	Explored Cell = 0x2
+	Flagged  Cell = 0x4
+)
+
+var (
+	ErrInvalidCoords   = errors.New("invalid row/col")
+	ErrAlreadyExplored = errors.New("cell already explored")
+	ErrCellFlagged     = errors.New("cell is flagged")
+	ErrGameOver        = errors.New("game is over")
+)
+
+// Initialise a new game, placing bombs using the given RNG. Passing an RNG
+// created from a fixed seed will always produce the same grid.
+func NewGame(gridSize, numBombs int, rng *rand.Rand) (*Game, error) {
+	if gridSize < 1 {
+		return nil, errors.New("grid size must be >0")
+	}
+
+	if numBombs < 0 || numBombs >= gridSize*gridSize {
+		return nil, errors.New("number of bombs must leave at least one safe cell")
+	}
+
+	return newGame(InitGrid(gridSize, numBombs, rng), numBombs), nil
+}
+
+// Construct a game around an existing grid and start its timer.
+func newGame(grid Grid, numBombs int) *Game {
+	game := &Game{
+		grid:     grid,
+		status:   InProgress,
+		numBombs: numBombs,
+		clock:    time.Now,
+	}
+	game.startTime = game.clock()
+
+	return game
+}
+
+// Initialise a new game from a seed value.
+func NewGameFromSeed(gridSize, numBombs int, seed int64) (*Game, error) {
+	return NewGame(gridSize, numBombs, rand.New(rand.NewSource(seed)))
+}
+
+// Return the current game status.
+func (game *Game) Status() GameStatus {
+	return game.status
+}
+
+// Return the number of successful moves (reveals and flag toggles) made.
+func (game *Game) NumMoves() int {
+	return game.numMoves
+}
+
+// Return the time spent playing. The timer stops when the game ends.
+func (game *Game) Elapsed() time.Duration {
+	if game.status != InProgress {
+		return game.elapsed
+	}
+
+	return game.elapsed + game.clock().Sub(game.startTime)
+}
+
+// Return the length of each side of the grid.
+func (game *Game) Size() int {
+	return len(game.grid)
+}
+
+// Return the number of bombs hidden in the grid.
+func (game *Game) NumBombs() int {
+	return game.numBombs
+}
+
+// Return the state of a single cell.
+func (game *Game) Cell(coords objects.GridCoords) (Cell, error) {
+	if !game.grid.validCoords(coords) {
+		return 0, ErrInvalidCoords
+	}
+
+	return game.grid[coords.Row][coords.Col], nil
+}
+
+// Return a character to represent a cell in the grid.
+func (game *Game) CellChar(coords objects.GridCoords) rune {
+	return game.grid.CellChar(coords)
+}
+
+// Explore a cell. If the cell has no neighbouring bombs, all of its
+// neighbours are explored too, spreading outwards until cells bordering
+// bombs are reached. Returns the coordinates of every newly explored cell,
+// in the order they were explored.
+func (game *Game) Reveal(coords objects.GridCoords) ([]objects.GridCoords, error) {
+	if err := game.checkMove(coords); err != nil {
+		return nil, err
+	}
+
+	cell := game.grid[coords.Row][coords.Col]
+	if cell&Explored != 0 {
+		return nil, ErrAlreadyExplored
+	}
+
+	if cell&Flagged != 0 {
+		return nil, ErrCellFlagged
+	}
+
+	game.numMoves++
+
+	var revealed []objects.GridCoords
+	toExplore := []objects.GridCoords{coords}
+
+	for len(toExplore) > 0 {
+		next := toExplore[len(toExplore)-1]
+		toExplore = toExplore[:len(toExplore)-1]
+
+		cell := game.grid[next.Row][next.Col]
+		if cell&(Explored|Flagged) != 0 {
+			continue
+		}
+
+		game.grid[next.Row][next.Col] |= Explored
+		revealed = append(revealed, next)
+
+		if cell&Bomb != 0 {
+			game.endGame(GameLost)
+			return revealed, nil
+		}
+
+		game.numExplored++
+		if game.grid.countNeighbourBombs(next) == 0 {
+			toExplore = append(toExplore, game.grid.unexploredNeighbours(next)...)
+		}
+	}
+
+	if game.numExplored == game.Size()*game.Size()-game.numBombs {
+		game.endGame(GameWon)
+	}
+
+	return revealed, nil
+}
+
+// Toggle the flag on an unexplored cell. Returns whether the cell is now
+// flagged.
+func (game *Game) ToggleFlag(coords objects.GridCoords) (bool, error) {
+	if err := game.checkMove(coords); err != nil {
+		return false, err
+	}
+
+	if game.grid[coords.Row][coords.Col]&Explored != 0 {
+		return false, ErrAlreadyExplored
+	}
+
+	game.numMoves++
+	game.grid[coords.Row][coords.Col] ^= Flagged
+	return game.grid[coords.Row][coords.Col]&Flagged != 0, nil
+}
+
+// Check that a move may be made at the given coordinates.
+func (game *Game) checkMove(coords objects.GridCoords) error {
+	if game.status != InProgress {
+		return ErrGameOver
+	}
+
+	if !game.grid.validCoords(coords) {
+		return ErrInvalidCoords
+	}
+
+	return nil
+}
+
+// Finish the game with the given status and stop the timer.
+func (game *Game) endGame(status GameStatus) {
+	game.elapsed += game.clock().Sub(game.startTime)
+	game.status = status
+}
+
+// Initialise a new grid.
+func InitGrid(size, numBombs int, rng *rand.Rand) Grid {
+	grid := make(Grid, size)
+	for i := range grid {
+		grid[i] = make([]Cell, size)
+	}
+
+	bombsPlaced := 0
+	for bombsPlaced != numBombs {
+		row := rng.Intn(size)
+		col := rng.Intn(size)
+		if grid[row][col]&Bomb == 0 {
+			grid[row][col] |= Bomb
+			bombsPlaced++
+		}
+	}
+
+	return grid
+}
+
+// Check if row and column indices are valid.
+func (grid Grid) validCoords(coords objects.GridCoords) bool {
+	if coords.Row < 0 || coords.Row >= len(grid) {
+		return false
+	}
+
+	if coords.Col < 0 || coords.Col >= len(grid[0]) {
+		return false
+	}
+
+	return true
+}
+
+// Return a character to represent a cell in the grid.
+func (grid Grid) CellChar(coords objects.GridCoords) rune {
+	cell := grid[coords.Row][coords.Col]
+	if cell&Explored != 0 {
+		if cell&Bomb != 0 {
+			return 'X'
+		} else {
+			neighbourBombs := grid.countNeighbourBombs(coords)
+			if neighbourBombs == 0 {
+				return ' '
+			} else {
+				return []rune(strconv.Itoa(neighbourBombs))[0]
+			}
+		}
+	} else {
+		if cell&Flagged != 0 {
+			return 'F'
+		} else {
+			return '.'
+		}
+	}
+}
+
+// Count the number of neighbouring bombs.
+func (grid Grid) countNeighbourBombs(coords objects.GridCoords) int {
+	bombCount := 0
+
+	for i := range objects.GridDirections {
+		newCoords := coords.MoveDirection(objects.GridDirections[i])
+		if grid.validCoords(newCoords) &&
+			grid[newCoords.Row][newCoords.Col]&Bomb != 0 {
+			bombCount++
+		}
+	}
+
+	return bombCount
+}
+
+// Return the coordinates of all neighbours that have not yet been explored.
+func (grid Grid) unexploredNeighbours(coords objects.GridCoords) []objects.GridCoords {
+	var neighbours []objects.GridCoords
+
+	for i := range objects.GridDirections {
+		newCoords := coords.MoveDirection(objects.GridDirections[i])
+		if grid.validCoords(newCoords) &&
+			grid[newCoords.Row][newCoords.Col]&Explored == 0 {
+			neighbours = append(neighbours, newCoords)
+		}
+	}
+
+	return neighbours
+}
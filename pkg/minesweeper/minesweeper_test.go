@@ -0,0 +1,215 @@
+package minesweeper
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ryanc414/ctci/pkg/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Construct a game with bombs at known positions.
+func gameWithBombs(size int, bombs ...objects.GridCoords) *Game {
+	grid := make(Grid, size)
+	for i := range grid {
+		grid[i] = make([]Cell, size)
+	}
+
+	for _, coords := range bombs {
+		grid[coords.Row][coords.Col] |= Bomb
+	}
+
+	return newGame(grid, len(bombs))
+}
+
+// Test that the same seed always produces the same grid.
+func TestDeterministicSeed(t *testing.T) {
+	game1, err := NewGameFromSeed(10, 15, 42)
+	require.NoError(t, err)
+
+	game2, err := NewGameFromSeed(10, 15, 42)
+	require.NoError(t, err)
+
+	assert.Equal(t, game1.grid, game2.grid)
+
+	numBombs := 0
+	for row := range game1.grid {
+		for col := range game1.grid[row] {
+			if game1.grid[row][col]&Bomb != 0 {
+				numBombs++
+			}
+		}
+	}
+	assert.Equal(t, 15, numBombs)
+
+	game3, err := NewGame(10, 15, rand.New(rand.NewSource(43)))
+	require.NoError(t, err)
+	assert.NotEqual(t, game1.grid, game3.grid)
+}
+
+// Test that invalid game parameters are rejected.
+func TestNewGameValidation(t *testing.T) {
+	_, err := NewGameFromSeed(0, 0, 1)
+	assert.Error(t, err)
+
+	_, err = NewGameFromSeed(3, 9, 1)
+	assert.Error(t, err)
+
+	_, err = NewGameFromSeed(3, -1, 1)
+	assert.Error(t, err)
+}
+
+// Test that revealing a bomb loses the game.
+func TestRevealBomb(t *testing.T) {
+	game := gameWithBombs(3, objects.GridCoords{Row: 1, Col: 1})
+
+	revealed, err := game.Reveal(objects.GridCoords{Row: 1, Col: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []objects.GridCoords{{Row: 1, Col: 1}}, revealed)
+	assert.Equal(t, GameLost, game.Status())
+	assert.Equal(t, 'X', game.CellChar(objects.GridCoords{Row: 1, Col: 1}))
+
+	_, err = game.Reveal(objects.GridCoords{Row: 0, Col: 0})
+	assert.Equal(t, ErrGameOver, err)
+}
+
+// Test that revealing an empty region floods outwards and wins the game once
+// every safe cell is explored.
+func TestRevealFloodFillWin(t *testing.T) {
+	game := gameWithBombs(4, objects.GridCoords{Row: 0, Col: 0})
+
+	revealed, err := game.Reveal(objects.GridCoords{Row: 3, Col: 3})
+	require.NoError(t, err)
+	assert.Len(t, revealed, 15)
+	assert.NotContains(t, revealed, objects.GridCoords{Row: 0, Col: 0})
+	assert.Equal(t, GameWon, game.Status())
+
+	assert.Equal(t, '1', game.CellChar(objects.GridCoords{Row: 1, Col: 1}))
+	assert.Equal(t, ' ', game.CellChar(objects.GridCoords{Row: 2, Col: 2}))
+	assert.Equal(t, '.', game.CellChar(objects.GridCoords{Row: 0, Col: 0}))
+}
+
+// Test that the game is only won once every safe cell has been explored.
+func TestRevealWinOneByOne(t *testing.T) {
+	game := gameWithBombs(
+		2, objects.GridCoords{Row: 0, Col: 0}, objects.GridCoords{Row: 1, Col: 1},
+	)
+
+	revealed, err := game.Reveal(objects.GridCoords{Row: 0, Col: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []objects.GridCoords{{Row: 0, Col: 1}}, revealed)
+	assert.Equal(t, InProgress, game.Status())
+
+	_, err = game.Reveal(objects.GridCoords{Row: 0, Col: 1})
+	assert.Equal(t, ErrAlreadyExplored, err)
+
+	_, err = game.Reveal(objects.GridCoords{Row: 1, Col: 0})
+	require.NoError(t, err)
+	assert.Equal(t, GameWon, game.Status())
+}
+
+// Test flagging cells.
+func TestToggleFlag(t *testing.T) {
+	game := gameWithBombs(3, objects.GridCoords{Row: 0, Col: 0})
+
+	flagged, err := game.ToggleFlag(objects.GridCoords{Row: 0, Col: 0})
+	require.NoError(t, err)
+	assert.True(t, flagged)
+	assert.Equal(t, 'F', game.CellChar(objects.GridCoords{Row: 0, Col: 0}))
+
+	// Flagged cells cannot be revealed.
+	_, err = game.Reveal(objects.GridCoords{Row: 0, Col: 0})
+	assert.Equal(t, ErrCellFlagged, err)
+
+	flagged, err = game.ToggleFlag(objects.GridCoords{Row: 0, Col: 0})
+	require.NoError(t, err)
+	assert.False(t, flagged)
+
+	// Flagging every cell does not win the game.
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			_, err = game.ToggleFlag(objects.GridCoords{Row: row, Col: col})
+			require.NoError(t, err)
+		}
+	}
+	assert.Equal(t, InProgress, game.Status())
+
+	_, err = game.ToggleFlag(objects.GridCoords{Row: 3, Col: 0})
+	assert.Equal(t, ErrInvalidCoords, err)
+}
+
+// Test that flood fill does not reveal flagged cells.
+func TestRevealSkipsFlagged(t *testing.T) {
+	game := gameWithBombs(3, objects.GridCoords{Row: 0, Col: 0})
+
+	_, err := game.ToggleFlag(objects.GridCoords{Row: 2, Col: 2})
+	require.NoError(t, err)
+
+	revealed, err := game.Reveal(objects.GridCoords{Row: 2, Col: 0})
+	require.NoError(t, err)
+	assert.NotContains(t, revealed, objects.GridCoords{Row: 2, Col: 2})
+	assert.Equal(t, InProgress, game.Status())
+
+	_, err = game.ToggleFlag(objects.GridCoords{Row: 2, Col: 2})
+	require.NoError(t, err)
+	revealed, err = game.Reveal(objects.GridCoords{Row: 2, Col: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []objects.GridCoords{{Row: 2, Col: 2}}, revealed)
+	assert.Equal(t, GameWon, game.Status())
+}
+
+// A fake clock that only moves when advanced.
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.now
+}
+
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.now = clock.now.Add(d)
+}
+
+// Construct a game with bombs at known positions, timed by a fake clock.
+func timedGameWithBombs(
+	clock *fakeClock, size int, bombs ...objects.GridCoords,
+) *Game {
+	game := gameWithBombs(size, bombs...)
+	game.clock = clock.Now
+	game.startTime = clock.Now()
+
+	return game
+}
+
+// Test that moves are counted and the timer stops when the game ends.
+func TestMovesAndTimer(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	game := timedGameWithBombs(
+		clock, 2, objects.GridCoords{Row: 0, Col: 0}, objects.GridCoords{Row: 1, Col: 1},
+	)
+
+	clock.Advance(3 * time.Second)
+	assert.Equal(t, 3*time.Second, game.Elapsed())
+
+	_, err := game.ToggleFlag(objects.GridCoords{Row: 0, Col: 0})
+	require.NoError(t, err)
+	_, err = game.Reveal(objects.GridCoords{Row: 0, Col: 1})
+	require.NoError(t, err)
+
+	// Invalid moves are not counted.
+	_, err = game.Reveal(objects.GridCoords{Row: 0, Col: 1})
+	require.Error(t, err)
+	assert.Equal(t, 2, game.NumMoves())
+
+	clock.Advance(2 * time.Second)
+	_, err = game.Reveal(objects.GridCoords{Row: 1, Col: 0})
+	require.NoError(t, err)
+	assert.Equal(t, GameWon, game.Status())
+	assert.Equal(t, 3, game.NumMoves())
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, 5*time.Second, game.Elapsed())
+}
@@ -0,0 +1,94 @@
+package minesweeper
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryanc414/ctci/pkg/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that a game in progress can be saved and resumed.
+func TestSaveLoad(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	game := timedGameWithBombs(
+		clock, 3, objects.GridCoords{Row: 0, Col: 0}, objects.GridCoords{Row: 2, Col: 2},
+	)
+
+	_, err := game.ToggleFlag(objects.GridCoords{Row: 0, Col: 0})
+	require.NoError(t, err)
+	_, err = game.Reveal(objects.GridCoords{Row: 0, Col: 1})
+	require.NoError(t, err)
+	clock.Advance(7 * time.Second)
+
+	path := filepath.Join(t.TempDir(), "game.json")
+	require.NoError(t, game.SaveFile(path))
+
+	loaded, err := LoadFile(path)
+	require.NoError(t, err)
+	loaded.clock = clock.Now
+	loaded.startTime = clock.Now()
+
+	assert.Equal(t, game.grid, loaded.grid)
+	assert.Equal(t, InProgress, loaded.Status())
+	assert.Equal(t, 2, loaded.NumBombs())
+	assert.Equal(t, 2, loaded.NumMoves())
+	assert.Equal(t, 7*time.Second, loaded.Elapsed())
+
+	// Finish the loaded game - explored cell counts must have been restored
+	// for the win to be detected.
+	clock.Advance(time.Second)
+	for _, coords := range []objects.GridCoords{
+		{Row: 1, Col: 0}, {Row: 1, Col: 1}, {Row: 1, Col: 2},
+		{Row: 2, Col: 1}, {Row: 0, Col: 2}, {Row: 2, Col: 0},
+	} {
+		require.Equal(t, InProgress, loaded.Status())
+		_, err = loaded.Reveal(coords)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, GameWon, loaded.Status())
+	assert.Equal(t, 8*time.Second, loaded.Elapsed())
+}
+
+// Test that inconsistent saved games are rejected.
+func TestLoadInvalid(t *testing.T) {
+	for _, input := range []string{
+		`not json`,
+		`{"grid": [], "num_bombs": 0}`,
+		`{"grid": [[0, 0], [0]], "num_bombs": 0}`,
+		`{"grid": [[1, 0], [0, 0]], "num_bombs": 2}`,
+		`{"grid": [[8, 0], [0, 0]], "num_bombs": 0}`,
+		`{"grid": [[1, 0], [0, 0]], "num_bombs": 1, "status": 7}`,
+	} {
+		_, err := Load(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+
+	rejections := []struct {
+		name  string
+		input string
+	}{
+		{"game won", `{"grid": [[1, 2], [2, 2]], "num_bombs": 1, "status": 1}`},
+		{"game lost", `{"grid": [[3, 0], [0, 0]], "num_bombs": 1, "status": 2}`},
+		{"negative moves", `{"grid": [[1, 0], [0, 0]], "num_bombs": 1, "num_moves": -1}`},
+		{"negative elapsed", `{"grid": [[1, 0], [0, 0]], "num_bombs": 1, "elapsed_ns": -1}`},
+		{"explored and flagged", `{"grid": [[1, 6], [0, 0]], "num_bombs": 1}`},
+	}
+
+	for _, test := range rejections {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(test.input))
+			assert.Error(t, err)
+		})
+	}
+
+	var buf bytes.Buffer
+	game := gameWithBombs(2, objects.GridCoords{Row: 1, Col: 1})
+	require.NoError(t, game.Save(&buf))
+	_, err := Load(&buf)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,139 @@
+package minesweeper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// A named combination of grid size and bomb count. Best times are tracked
+// separately for each difficulty.
+type Difficulty struct {
+	Name     string
+	Size     int
+	NumBombs int
+}
+
+var Difficulties = [...]Difficulty{
+	{Name: "easy", Size: 8, NumBombs: 10},
+	{Name: "medium", Size: 12, NumBombs: 25},
+	{Name: "hard", Size: 16, NumBombs: 40},
+}
+
+// Look up one of the standard difficulties by name.
+func FindDifficulty(name string) (Difficulty, error) {
+	for _, difficulty := range Difficulties {
+		if difficulty.Name == name {
+			return difficulty, nil
+		}
+	}
+
+	return Difficulty{}, fmt.Errorf("unknown difficulty %q", name)
+}
+
+// Return the difficulty name to record scores under for a game. Games that do
+// not match a standard difficulty get a name describing their grid.
+func DifficultyName(game *Game) string {
+	for _, difficulty := range Difficulties {
+		if difficulty.Size == game.Size() &&
+			difficulty.NumBombs == game.NumBombs() {
+			return difficulty.Name
+		}
+	}
+
+	return fmt.Sprintf("custom-%dx%d-%d", game.Size(), game.Size(), game.NumBombs())
+}
+
+// Only this many scores are kept per difficulty.
+const MaxScores = 10
+
+// A single completed game.
+type Score struct {
+	Elapsed  time.Duration `json:"elapsed_ns"`
+	NumMoves int           `json:"num_moves"`
+	Date     time.Time     `json:"date"`
+}
+
+// Best scores for each difficulty, ordered fastest first.
+type ScoreTable struct {
+	Best map[string][]Score `json:"best"`
+}
+
+// Create an empty score table.
+func NewScoreTable() *ScoreTable {
+	return &ScoreTable{Best: make(map[string][]Score)}
+}
+
+// Load a score table from a JSON file. A missing file gives an empty table,
+// so the first game played creates it.
+func LoadScores(path string) (*ScoreTable, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewScoreTable(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	table := NewScoreTable()
+	if err := json.Unmarshal(data, table); err != nil {
+		return nil, err
+	}
+
+	if table.Best == nil {
+		table.Best = make(map[string][]Score)
+	}
+
+	return table, nil
+}
+
+// Save the score table to a JSON file.
+func (table *ScoreTable) Save(path string) error {
+	data, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Record a score for a difficulty. Returns the 1-based rank of the new score,
+// or 0 if it was not good enough to make the table.
+func (table *ScoreTable) Add(difficulty string, score Score) int {
+	scores := table.Best[difficulty]
+
+	rank := sort.Search(len(scores), func(i int) bool {
+		return scoreLess(score, scores[i])
+	})
+	if rank >= MaxScores {
+		return 0
+	}
+
+	scores = append(scores, Score{})
+	copy(scores[rank+1:], scores[rank:])
+	scores[rank] = score
+
+	if len(scores) > MaxScores {
+		scores = scores[:MaxScores]
+	}
+	table.Best[difficulty] = scores
+
+	return rank + 1
+}
+
+// Return the best scores for a difficulty, fastest first.
+func (table *ScoreTable) Top(difficulty string) []Score {
+	return table.Best[difficulty]
+}
+
+// Compare two scores: faster times win, with fewer moves breaking ties.
+func scoreLess(a, b Score) bool {
+	if a.Elapsed != b.Elapsed {
+		return a.Elapsed < b.Elapsed
+	}
+
+	return a.NumMoves < b.NumMoves
+}
@@ -0,0 +1,71 @@
+package minesweeper
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ryanc414/ctci/pkg/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test difficulty lookup and naming.
+func TestDifficulty(t *testing.T) {
+	difficulty, err := FindDifficulty("medium")
+	require.NoError(t, err)
+	assert.Equal(t, 12, difficulty.Size)
+
+	_, err = FindDifficulty("impossible")
+	assert.Error(t, err)
+
+	game, err := NewGameFromSeed(8, 10, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "easy", DifficultyName(game))
+
+	game = gameWithBombs(3, objects.GridCoords{Row: 0, Col: 0})
+	assert.Equal(t, "custom-3x3-1", DifficultyName(game))
+}
+
+// Test that scores are ranked and capped per difficulty.
+func TestScoreTable(t *testing.T) {
+	table := NewScoreTable()
+
+	assert.Equal(t, 1, table.Add("easy", Score{Elapsed: 30 * time.Second}))
+	assert.Equal(t, 1, table.Add("easy", Score{Elapsed: 20 * time.Second}))
+	assert.Equal(t, 3, table.Add("easy", Score{Elapsed: 40 * time.Second}))
+	assert.Equal(t, 2, table.Add("easy", Score{Elapsed: 20 * time.Second, NumMoves: 5}))
+	assert.Equal(t, 1, table.Add("hard", Score{Elapsed: time.Hour}))
+
+	top := table.Top("easy")
+	require.Len(t, top, 4)
+	assert.Equal(t, 20*time.Second, top[0].Elapsed)
+	assert.Equal(t, 5, top[1].NumMoves)
+	assert.Equal(t, 40*time.Second, top[3].Elapsed)
+
+	for i := 0; i < MaxScores; i++ {
+		table.Add("easy", Score{Elapsed: time.Second})
+	}
+	assert.Len(t, table.Top("easy"), MaxScores)
+	assert.Equal(t, 0, table.Add("easy", Score{Elapsed: time.Minute}))
+}
+
+// Test that the score table round-trips through a file.
+func TestScoresSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores.json")
+
+	table, err := LoadScores(path)
+	require.NoError(t, err)
+	assert.Empty(t, table.Top("easy"))
+
+	date := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	table.Add("easy", Score{Elapsed: 12 * time.Second, NumMoves: 9, Date: date})
+	require.NoError(t, table.Save(path))
+
+	loaded, err := LoadScores(path)
+	require.NoError(t, err)
+	require.Len(t, loaded.Top("easy"), 1)
+	assert.Equal(t, 12*time.Second, loaded.Top("easy")[0].Elapsed)
+	assert.Equal(t, 9, loaded.Top("easy")[0].NumMoves)
+	assert.True(t, date.Equal(loaded.Top("easy")[0].Date))
+}
@@ -0,0 +1,161 @@
+package montecarlo
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// The default number of trials that share an RNG stream.
+const DefaultChunkSize = 10000
+
+// An Accumulator collects the results of trials.
+type Accumulator interface {
+	// Add the results in another accumulator, made by the same function, to
+	// this one.
+	Merge(other Accumulator)
+}
+
+// Settings for a Monte Carlo run.
+type Config struct {
+	Trials    int   // the number of trials to run, at most
+	Seed      int64 // the seed that all RNG streams are derived from
+	Workers   int   // number of goroutines to use; zero for GOMAXPROCS
+	ChunkSize int   // trials per RNG stream; zero for DefaultChunkSize
+
+	// If set, the run stops early once this returns true for the results
+	// so far.
+	Converged func(acc Accumulator) bool
+}
+
+// Run many independent trials in parallel.
+//
+// Trials are split into chunks, which are shared out between a pool of
+// workers. Each chunk has its own accumulator and its own RNG stream, seeded
+// from the config seed and the chunk's index. Chunks are merged in order, and
+// convergence is checked after each one, so the result only depends on the
+// seed - not on the number of workers or which worker ran which chunk.
+//
+// Run returns the merged accumulator, or the error from the first trial to
+// fail.
+func Run(
+	config Config,
+	newAccumulator func() Accumulator,
+	trial func(rng *rand.Rand, acc Accumulator) error,
+) (Accumulator, error) {
+	if config.Trials < 1 {
+		return nil, errors.New("must run at least one trial")
+	}
+
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	chunkSize := config.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	numChunks := (config.Trials + chunkSize - 1) / chunkSize
+
+	type chunkResult struct {
+		index int
+		acc   Accumulator
+		err   error
+	}
+
+	chunks := make(chan int)
+	results := make(chan chunkResult)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(chunks)
+
+		for chunk := 0; chunk < numChunks; chunk++ {
+			select {
+			case chunks <- chunk:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for chunk := range chunks {
+				numTrials := config.Trials - chunk*chunkSize
+				if numTrials > chunkSize {
+					numTrials = chunkSize
+				}
+
+				acc := newAccumulator()
+				err := runChunk(config.Seed, chunk, numTrials, acc, trial)
+
+				select {
+				case results <- chunkResult{index: chunk, acc: acc, err: err}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	// Merge the chunks in order as they finish.
+	total := newAccumulator()
+	finished := make(map[int]chunkResult)
+	for next := 0; next < numChunks; {
+		result := <-results
+		finished[result.index] = result
+
+		for {
+			result, ok := finished[next]
+			if !ok {
+				break
+			}
+			delete(finished, next)
+			next++
+
+			if result.err != nil {
+				return nil, result.err
+			}
+
+			total.Merge(result.acc)
+			if config.Converged != nil && config.Converged(total) {
+				return total, nil
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// Run the trials in a single chunk.
+func runChunk(
+	seed int64,
+	chunk, numTrials int,
+	acc Accumulator,
+	trial func(rng *rand.Rand, acc Accumulator) error,
+) error {
+	rng := NewRand(StreamSeed(seed, chunk))
+
+	for i := 0; i < numTrials; i++ {
+		if err := trial(rng, acc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
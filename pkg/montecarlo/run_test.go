@@ -0,0 +1,131 @@
+package montecarlo
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Observe a uniform random number in each trial.
+func uniformTrial(rng *rand.Rand, acc Accumulator) error {
+	acc.(*Observations).Add(rng.Float64())
+	return nil
+}
+
+func newObservations() Accumulator {
+	return NewObservations()
+}
+
+// Test that all trials are run, and that the result only depends on the seed.
+func TestRunDeterministic(t *testing.T) {
+	var results []*Observations
+	for _, workers := range []int{1, 2, 3, 8} {
+		acc, err := Run(
+			Config{Trials: 10500, Seed: 42, Workers: workers, ChunkSize: 1000},
+			newObservations,
+			uniformTrial,
+		)
+		require.NoError(t, err)
+		results = append(results, acc.(*Observations))
+	}
+
+	for _, obs := range results {
+		assert.Equal(t, int64(10500), obs.Stats.Count())
+		assert.Equal(t, results[0].Stats, obs.Stats)
+		assert.Equal(t, results[0].Quantiles, obs.Quantiles)
+	}
+
+	assert.InDelta(t, 0.5, results[0].Stats.Mean(), 0.01)
+	assert.InDelta(t, 1.0/12, results[0].Stats.Variance(), 0.005)
+
+	other, err := Run(
+		Config{Trials: 10500, Seed: 43, ChunkSize: 1000},
+		newObservations,
+		uniformTrial,
+	)
+	require.NoError(t, err)
+	assert.NotEqual(t, results[0].Stats, other.(*Observations).Stats)
+}
+
+// Test that runs stop once they converge, at the same point whatever the
+// number of workers.
+func TestRunConverged(t *testing.T) {
+	config := Config{
+		Trials:    1000000,
+		Seed:      1,
+		ChunkSize: 100,
+		Converged: StopWhenPrecise(
+			func(acc Accumulator) Stats { return acc.(*Observations).Stats },
+			0.01,
+			500,
+		),
+	}
+
+	var counts []int64
+	for _, workers := range []int{1, 4} {
+		config.Workers = workers
+		acc, err := Run(config, newObservations, uniformTrial)
+		require.NoError(t, err)
+
+		stats := acc.(*Observations).Stats
+		assert.LessOrEqual(t, stats.HalfWidth(Z95), 0.01)
+		assert.Zero(t, stats.Count()%100)
+		counts = append(counts, stats.Count())
+	}
+
+	// sd = 0.29, so about 3200 trials are needed.
+	assert.Equal(t, counts[0], counts[1])
+	assert.Less(t, counts[0], int64(5000))
+}
+
+// Test that the first error stops the run.
+func TestRunError(t *testing.T) {
+	errTrial := errors.New("trial failed")
+	trials := 0
+
+	_, err := Run(
+		Config{Trials: 100, Workers: 1, ChunkSize: 10},
+		newObservations,
+		func(rng *rand.Rand, acc Accumulator) error {
+			trials++
+			if trials == 25 {
+				return errTrial
+			}
+			return nil
+		},
+	)
+	assert.Equal(t, errTrial, err)
+
+	_, err = Run(Config{}, newObservations, uniformTrial)
+	assert.Error(t, err)
+}
+
+// Test that stream seeds are distinct.
+func TestStreamSeed(t *testing.T) {
+	seen := make(map[int64]bool)
+	for _, seed := range []int64{0, 1, 2} {
+		for stream := 0; stream < 100; stream++ {
+			s := StreamSeed(seed, stream)
+			assert.False(t, seen[s])
+			seen[s] = true
+		}
+	}
+
+	assert.Equal(t, StreamSeed(7, 3), StreamSeed(7, 3))
+	assert.Equal(t, int64(5), ResolveSeed(5))
+	assert.NotZero(t, ResolveSeed(0))
+}
+
+func BenchmarkRun(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, err := Run(
+			Config{Trials: 100000, Seed: int64(i)},
+			newObservations,
+			uniformTrial,
+		)
+		require.NoError(b, err)
+	}
+}
@@ -0,0 +1,44 @@
+package montecarlo
+
+import (
+	crypto_rand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+)
+
+// Generate a random seed value from the crypto RNG. Record the seed to be
+// able to reproduce a run.
+func RandomSeed() int64 {
+	var b [8]byte
+	_, err := crypto_rand.Read(b[:])
+	if err != nil {
+		panic("Cannot seed RNG")
+	}
+
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// Return the seed, or a random seed if it is zero. Commands use zero to mean
+// "pick a seed for me".
+func ResolveSeed(seed int64) int64 {
+	if seed == 0 {
+		return RandomSeed()
+	}
+
+	return seed
+}
+
+// Return a new RNG with the given seed.
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// Derive the seed for one of many independent RNG streams from a single run
+// seed. Consecutive streams are spread over the whole range of seeds by the
+// SplitMix64 finaliser, so that they are unrelated to each other.
+func StreamSeed(seed int64, stream int) int64 {
+	z := uint64(seed) + uint64(stream+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
@@ -0,0 +1,280 @@
+package montecarlo
+
+import (
+	"math"
+	"sort"
+)
+
+// The z-score for a two-sided 95% confidence interval.
+const Z95 = 1.959963984540054
+
+// Streaming estimates of the mean and variance of a series of values, using
+// Welford's algorithm. Stats from separate runs can be merged exactly.
+type Stats struct {
+	count    int64
+	mean     float64
+	sumSq    float64 // sum of squared differences from the mean
+	min, max float64
+}
+
+// Add a value.
+func (stats *Stats) Add(x float64) {
+	if stats.count == 0 || x < stats.min {
+		stats.min = x
+	}
+	if stats.count == 0 || x > stats.max {
+		stats.max = x
+	}
+
+	stats.count++
+	delta := x - stats.mean
+	stats.mean += delta / float64(stats.count)
+	stats.sumSq += delta * (x - stats.mean)
+}
+
+// Add all the values from other, as if they had been added to these stats.
+func (stats *Stats) Merge(other Stats) {
+	if other.count == 0 {
+		return
+	}
+	if stats.count == 0 {
+		*stats = other
+		return
+	}
+
+	count := stats.count + other.count
+	delta := other.mean - stats.mean
+	stats.mean += delta * float64(other.count) / float64(count)
+	stats.sumSq += other.sumSq +
+		delta*delta*float64(stats.count)*float64(other.count)/float64(count)
+	stats.count = count
+	stats.min = math.Min(stats.min, other.min)
+	stats.max = math.Max(stats.max, other.max)
+}
+
+// Return the number of values added.
+func (stats Stats) Count() int64 {
+	return stats.count
+}
+
+// Return the mean of the values, or zero if there are none.
+func (stats Stats) Mean() float64 {
+	return stats.mean
+}
+
+// Return the sample variance of the values, or zero if there are fewer than
+// two.
+func (stats Stats) Variance() float64 {
+	if stats.count < 2 {
+		return 0
+	}
+
+	return stats.sumSq / float64(stats.count-1)
+}
+
+// Return the sample standard deviation of the values.
+func (stats Stats) StdDev() float64 {
+	return math.Sqrt(stats.Variance())
+}
+
+// Return the standard error of the mean.
+func (stats Stats) StdErr() float64 {
+	if stats.count == 0 {
+		return math.Inf(1)
+	}
+
+	return stats.StdDev() / math.Sqrt(float64(stats.count))
+}
+
+// Return the half-width of the confidence interval for the mean, for the
+// given z-score. It is infinite until there are at least two values.
+func (stats Stats) HalfWidth(z float64) float64 {
+	if stats.count < 2 {
+		return math.Inf(1)
+	}
+
+	return z * stats.StdErr()
+}
+
+// Return the confidence interval for the mean, for the given z-score.
+func (stats Stats) ConfidenceInterval(z float64) [2]float64 {
+	halfWidth := stats.HalfWidth(z)
+	return [2]float64{stats.mean - halfWidth, stats.mean + halfWidth}
+}
+
+// Return the smallest value added.
+func (stats Stats) Min() float64 {
+	return stats.min
+}
+
+// Return the largest value added.
+func (stats Stats) Max() float64 {
+	return stats.max
+}
+
+// A mergeable sketch of a distribution, for estimating quantiles without
+// storing every value. Values are counted in buckets whose bounds grow
+// geometrically, so every quantile is estimated to within a fixed relative
+// error. Sketches with the same accuracy can be merged.
+type Sketch struct {
+	gamma    float64
+	logGamma float64
+	positive map[int]int64 // bucket counts for positive values
+	negative map[int]int64 // bucket counts for negative values, by magnitude
+	zeros    int64
+	count    int64
+}
+
+// Initialise a new sketch whose quantiles are within relativeAccuracy of
+// the true values, e.g. 0.01 for 1%.
+func NewSketch(relativeAccuracy float64) *Sketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		panic("relative accuracy must be between 0 and 1")
+	}
+
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &Sketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		positive: make(map[int]int64),
+		negative: make(map[int]int64),
+	}
+}
+
+// Add a value.
+func (sketch *Sketch) Add(x float64) {
+	sketch.count++
+
+	switch {
+	case x > 0:
+		sketch.positive[sketch.bucket(x)]++
+
+	case x < 0:
+		sketch.negative[sketch.bucket(-x)]++
+
+	default:
+		sketch.zeros++
+	}
+}
+
+// Add all the values from other, which must have the same accuracy.
+func (sketch *Sketch) Merge(other *Sketch) {
+	if other.gamma != sketch.gamma {
+		panic("cannot merge sketches with different accuracies")
+	}
+
+	for bucket, count := range other.positive {
+		sketch.positive[bucket] += count
+	}
+	for bucket, count := range other.negative {
+		sketch.negative[bucket] += count
+	}
+	sketch.zeros += other.zeros
+	sketch.count += other.count
+}
+
+// Return the number of values added.
+func (sketch *Sketch) Count() int64 {
+	return sketch.count
+}
+
+// Return an estimate of the value at quantile q, between 0 and 1, or zero if
+// the sketch is empty.
+func (sketch *Sketch) Quantile(q float64) float64 {
+	if sketch.count == 0 {
+		return 0
+	}
+
+	rank := int64(q * float64(sketch.count-1))
+	if rank < 0 {
+		rank = 0
+	}
+
+	// Walk the buckets from the most negative value upwards.
+	negative := sortedBuckets(sketch.negative)
+	for i := len(negative) - 1; i >= 0; i-- {
+		rank -= sketch.negative[negative[i]]
+		if rank < 0 {
+			return -sketch.value(negative[i])
+		}
+	}
+
+	rank -= sketch.zeros
+	if rank < 0 {
+		return 0
+	}
+
+	positive := sortedBuckets(sketch.positive)
+	for _, bucket := range positive {
+		rank -= sketch.positive[bucket]
+		if rank < 0 {
+			return sketch.value(bucket)
+		}
+	}
+
+	return sketch.value(positive[len(positive)-1])
+}
+
+// Return the bucket for a positive value. Bucket i holds values in
+// (gamma^(i-1), gamma^i].
+func (sketch *Sketch) bucket(x float64) int {
+	return int(math.Ceil(math.Log(x) / sketch.logGamma))
+}
+
+// Return the estimate for values in a bucket, which is within the relative
+// accuracy of every value in it.
+func (sketch *Sketch) value(bucket int) float64 {
+	return 2 * math.Pow(sketch.gamma, float64(bucket)) / (sketch.gamma + 1)
+}
+
+// Return the buckets in a map in ascending order.
+func sortedBuckets(counts map[int]int64) []int {
+	buckets := make([]int, 0, len(counts))
+	for bucket := range counts {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+
+	return buckets
+}
+
+// The relative accuracy of the sketches kept by Observations.
+const ObservationAccuracy = 0.01
+
+// An Accumulator for a single measurement taken in each trial, tracking its
+// mean, variance and quantiles.
+type Observations struct {
+	Stats     Stats
+	Quantiles *Sketch
+}
+
+// Initialise a new set of observations.
+func NewObservations() *Observations {
+	return &Observations{Quantiles: NewSketch(ObservationAccuracy)}
+}
+
+// Record a measurement.
+func (obs *Observations) Add(x float64) {
+	obs.Stats.Add(x)
+	obs.Quantiles.Add(x)
+}
+
+// Add the measurements in another set of observations.
+func (obs *Observations) Merge(other Accumulator) {
+	o := other.(*Observations)
+	obs.Stats.Merge(o.Stats)
+	obs.Quantiles.Merge(o.Quantiles)
+}
+
+// Return a convergence test for Config.Converged, which stops a run once at
+// least minTrials values have been observed and the 95% confidence interval
+// for the mean is no wider than ±tolerance. The stats function picks out the
+// estimate to test from the results so far.
+func StopWhenPrecise(
+	stats func(acc Accumulator) Stats, tolerance float64, minTrials int64,
+) func(acc Accumulator) bool {
+	return func(acc Accumulator) bool {
+		s := stats(acc)
+		return s.Count() >= minTrials && s.HalfWidth(Z95) <= tolerance
+	}
+}
@@ -0,0 +1,108 @@
+package montecarlo
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test the streaming stats against a direct calculation.
+func TestStats(t *testing.T) {
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+
+	var stats Stats
+	assert.True(t, math.IsInf(stats.HalfWidth(Z95), 1))
+	for _, x := range values {
+		stats.Add(x)
+	}
+
+	assert.Equal(t, int64(8), stats.Count())
+	assert.InDelta(t, 5, stats.Mean(), 1e-12)
+	assert.InDelta(t, 32.0/7, stats.Variance(), 1e-12)
+	assert.InDelta(t, math.Sqrt(32.0/7/8), stats.StdErr(), 1e-12)
+	assert.Equal(t, 2.0, stats.Min())
+	assert.Equal(t, 9.0, stats.Max())
+
+	ci := stats.ConfidenceInterval(Z95)
+	assert.InDelta(t, 5-Z95*stats.StdErr(), ci[0], 1e-12)
+	assert.InDelta(t, 5+Z95*stats.StdErr(), ci[1], 1e-12)
+}
+
+// Test that merging stats matches adding every value to one.
+func TestStatsMerge(t *testing.T) {
+	rng := NewRand(1)
+
+	var all, left, right, empty Stats
+	for i := 0; i < 1000; i++ {
+		x := rng.NormFloat64()*3 + 10
+		all.Add(x)
+		if i < 300 {
+			left.Add(x)
+		} else {
+			right.Add(x)
+		}
+	}
+
+	left.Merge(right)
+	left.Merge(empty)
+	assert.Equal(t, all.Count(), left.Count())
+	assert.InDelta(t, all.Mean(), left.Mean(), 1e-9)
+	assert.InDelta(t, all.Variance(), left.Variance(), 1e-9)
+	assert.Equal(t, all.Min(), left.Min())
+	assert.Equal(t, all.Max(), left.Max())
+
+	empty.Merge(all)
+	assert.Equal(t, all, empty)
+}
+
+// Test that sketch quantiles are within the relative accuracy of the exact
+// quantiles.
+func TestSketchQuantile(t *testing.T) {
+	rng := NewRand(2)
+	sketch := NewSketch(0.01)
+	assert.Equal(t, 0.0, sketch.Quantile(0.5))
+
+	var values []float64
+	for i := 0; i < 10000; i++ {
+		x := rng.ExpFloat64() * 100
+		if i%10 == 0 {
+			x = -x
+		}
+		if i%100 == 0 {
+			x = 0
+		}
+		values = append(values, x)
+		sketch.Add(x)
+	}
+	sort.Float64s(values)
+
+	assert.Equal(t, int64(10000), sketch.Count())
+	for _, q := range []float64{0, 0.05, 0.1, 0.25, 0.5, 0.9, 0.99, 1} {
+		exact := values[int(q*float64(len(values)-1))]
+		assert.InDelta(
+			t, exact, sketch.Quantile(q), math.Abs(exact)*0.01+1e-9, "q = %v", q,
+		)
+	}
+}
+
+// Test that merged sketches give the same quantiles as one sketch.
+func TestSketchMerge(t *testing.T) {
+	all := NewSketch(0.02)
+	left := NewSketch(0.02)
+	right := NewSketch(0.02)
+
+	for i := 1; i <= 1000; i++ {
+		all.Add(float64(i))
+		if i%2 == 0 {
+			left.Add(float64(i))
+		} else {
+			right.Add(float64(i))
+		}
+	}
+
+	left.Merge(right)
+	assert.Equal(t, all, left)
+	assert.Panics(t, func() { left.Merge(NewSketch(0.01)) })
+}
@@ -2,10 +2,10 @@ package objects
 
 import (
 	"container/list"
-	crypto_rand "crypto/rand"
-	"encoding/binary"
 	"errors"
 	math_rand "math/rand"
+
+	"github.com/ryanc414/ctci/pkg/montecarlo"
 )
 
 // The CircularArray type gives an array-like interface but, unlike a regular
@@ -58,14 +58,12 @@ func (circ CircularArray) Rotate(numPlaces int) CircularArray {
 }
 
 // Seed the RNG so that different results are produced each time.
+//
+// Deprecated: seeding the global RNG makes runs impossible to reproduce. Use
+// montecarlo.ResolveSeed and montecarlo.NewRand to get a private, seeded RNG
+// instead.
 func SeedRng() {
-	var b [8]byte
-	_, err := crypto_rand.Read(b[:])
-	if err != nil {
-		panic("Cannot send RNG")
-	}
-
-	math_rand.Seed(int64(binary.LittleEndian.Uint64(b[:])))
+	math_rand.Seed(montecarlo.RandomSeed())
 }
 
 // Represents a direction of movement on a 2D grid.
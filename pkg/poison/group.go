@@ -0,0 +1,379 @@
+package poison
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// A MultiStrategy finds every poisoned bottle, when more than one bottle may
+// be poisoned.
+type MultiStrategy interface {
+	Name() string
+	FindPoisons(lab *Lab) ([]int, error)
+}
+
+// Find up to K poisoned bottles in a single round, using a K-disjunct test
+// design: no bottle's strips are all covered by the strips of any K other
+// bottles. Every bottle on a negative strip must be safe, and with a
+// K-disjunct design every safe bottle is on at least one negative strip, so
+// the bottles left over are exactly the poisoned ones.
+type Disjunct struct {
+	K int
+}
+
+func (strategy Disjunct) Name() string {
+	return fmt.Sprintf("disjunct design (k=%v)", strategy.K)
+}
+
+func (strategy Disjunct) FindPoisons(lab *Lab) ([]int, error) {
+	plan, err := DisjunctPlan(lab.NumBottles(), strategy.K)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plan) > lab.StripsLeft() {
+		return nil, fmt.Errorf(
+			"%w: %v bottles need %v strips, but only %v are available",
+			ErrNotEnoughStrips,
+			lab.NumBottles(),
+			len(plan),
+			lab.StripsLeft(),
+		)
+	}
+
+	results, err := lab.RunRound(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	if strategy.K == 1 {
+		return decodeShiftedBinary(results), nil
+	}
+
+	safe := make([]bool, lab.NumBottles())
+	for strip, positive := range results {
+		if !positive {
+			for _, bottle := range plan[strip] {
+				safe[bottle] = true
+			}
+		}
+	}
+
+	var poisoned []int
+	for bottle, isSafe := range safe {
+		if !isSafe {
+			poisoned = append(poisoned, bottle)
+		}
+	}
+
+	if len(poisoned) > strategy.K {
+		return nil, fmt.Errorf("more than %v bottles are poisoned", strategy.K)
+	}
+
+	return poisoned, nil
+}
+
+// Return the number of strips a single round needs to find up to k poisoned
+// bottles among numBottles, using DisjunctPlan.
+func StripsForDisjunct(numBottles, k int) int {
+	plan, err := DisjunctPlan(numBottles, k)
+	if err != nil {
+		return 0
+	}
+
+	return len(plan)
+}
+
+// Return a test design that finds up to k poisoned bottles in a single round.
+// plan[i] lists the bottles that go on strip i.
+//
+// For k=1, bottle i goes on the strips given by the binary digits of i+1, so
+// a round with no positive strips means no bottle is poisoned. Otherwise the
+// Kautz-Singleton construction is used: each bottle is given a distinct
+// polynomial of degree less than m over the integers mod a prime q, and is
+// put on strip (x, p(x)) for each of the first t values of x. Two distinct
+// polynomials agree on at most m-1 points, so with t = k(m-1)+1 no bottle is
+// covered by k others. The q and m giving the fewest strips are chosen. When
+// m=1 this degenerates into testing every bottle on its own strip.
+func DisjunctPlan(numBottles, k int) ([][]int, error) {
+	if numBottles < 0 || k < 0 {
+		return nil, errors.New("bottles and k must be >= 0")
+	}
+
+	if k == 0 || numBottles == 0 {
+		return nil, nil
+	}
+
+	if k == 1 {
+		return shiftedBinaryPlan(numBottles), nil
+	}
+
+	bestQ, bestM, bestStrips := 0, 0, 0
+	for m := 1; ; m++ {
+		t := k*(m-1) + 1
+		q := nextPrime(maxInt(t, intRoot(numBottles, m)))
+		strips := t * q
+
+		if bestStrips == 0 || strips < bestStrips {
+			bestQ, bestM, bestStrips = q, m, strips
+		}
+
+		// Larger m can only need more strips once t alone exceeds the best.
+		if t*t >= bestStrips {
+			break
+		}
+	}
+
+	return kautzSingletonPlan(numBottles, bestQ, bestM, k*(bestM-1)+1), nil
+}
+
+// Build the Kautz-Singleton design for the given parameters, leaving out any
+// strips that no bottle goes on.
+func kautzSingletonPlan(numBottles, q, m, t int) [][]int {
+	strips := make([][]int, t*q)
+
+	for bottle := 0; bottle < numBottles; bottle++ {
+		coeffs := make([]int, m)
+		for j, rest := 0, bottle; j < m; j++ {
+			coeffs[j] = rest % q
+			rest /= q
+		}
+
+		for x := 0; x < t; x++ {
+			// Evaluate the polynomial using Horner's method.
+			y := 0
+			for j := m - 1; j >= 0; j-- {
+				y = (y*x + coeffs[j]) % q
+			}
+
+			strips[x*q+y] = append(strips[x*q+y], bottle)
+		}
+	}
+
+	var plan [][]int
+	for _, strip := range strips {
+		if len(strip) > 0 {
+			plan = append(plan, strip)
+		}
+	}
+
+	return plan
+}
+
+// Put bottle i on the strips given by the binary digits of i+1.
+func shiftedBinaryPlan(numBottles int) [][]int {
+	plan := make([][]int, bits.Len(uint(numBottles)))
+	for bottle := 0; bottle < numBottles; bottle++ {
+		for strip := range plan {
+			if (bottle+1)&(1<<strip) != 0 {
+				plan[strip] = append(plan[strip], bottle)
+			}
+		}
+	}
+
+	return plan
+}
+
+// Decode the results of a shiftedBinaryPlan.
+func decodeShiftedBinary(results []bool) []int {
+	code := 0
+	for strip, positive := range results {
+		if positive {
+			code |= 1 << strip
+		}
+	}
+
+	if code == 0 {
+		return nil
+	}
+
+	return []int{code - 1}
+}
+
+// Find every poisoned bottle over several rounds by repeatedly splitting
+// groups that test positive. The first round spreads all the bottles over
+// every strip. After that, the strips left are shared out between the groups
+// that may still hold poison. A group known to hold poison is split into one
+// more part than it has strips, like Splitting, and the last part is only
+// tested if another part tests positive. Groups that test negative are safe.
+// If K is set, the search stops as soon as K poisoned bottles are found,
+// since the rest must then be safe.
+type AdaptiveSplitting struct {
+	K int // the most bottles that can be poisoned, or zero if unknown
+}
+
+func (strategy AdaptiveSplitting) Name() string {
+	if strategy.K == 0 {
+		return "adaptive splitting"
+	}
+
+	return fmt.Sprintf("adaptive splitting (k=%v)", strategy.K)
+}
+
+// A group of bottles that may hold poison.
+type suspectGroup struct {
+	bottles  []int
+	positive bool // true if the group is known to hold poison
+}
+
+// How a suspect group was split up for a round of tests.
+type suspectSplit struct {
+	first     int   // index of the group's first part in the round
+	numTested int   // number of parts tested
+	untested  []int // the part left out, for groups known to hold poison
+}
+
+func (strategy AdaptiveSplitting) FindPoisons(lab *Lab) ([]int, error) {
+	var poisoned []int
+	var suspects []suspectGroup
+
+	addPositive := func(bottles []int) {
+		if len(bottles) == 1 {
+			poisoned = append(poisoned, bottles[0])
+		} else {
+			suspects = append(
+				suspects, suspectGroup{bottles: bottles, positive: true},
+			)
+		}
+	}
+
+	if lab.NumBottles() > 0 {
+		all := make([]int, lab.NumBottles())
+		for i := range all {
+			all[i] = i
+		}
+		suspects = append(suspects, suspectGroup{bottles: all})
+	}
+
+	for len(suspects) > 0 {
+		if strategy.K > 0 && len(poisoned) >= strategy.K {
+			break
+		}
+
+		stripsLeft := lab.StripsLeft()
+		if stripsLeft == 0 {
+			return nil, ErrNotEnoughStrips
+		}
+
+		share := stripsLeft / len(suspects)
+		if share < 1 {
+			share = 1
+		}
+
+		// Split up as many groups as there are strips for. The rest wait for
+		// the next round.
+		var parts [][]int
+		var splits []suspectSplit
+		var waiting []suspectGroup
+
+		for _, suspect := range suspects {
+			numTested := stripsLeft - len(parts)
+			if numTested == 0 {
+				waiting = append(waiting, suspect)
+				continue
+			}
+			if numTested > share {
+				numTested = share
+			}
+
+			split := suspectSplit{first: len(parts)}
+			var groupParts [][]int
+			if suspect.positive {
+				groupParts = splitGroups(suspect.bottles, numTested+1)
+				split.untested = groupParts[len(groupParts)-1]
+				groupParts = groupParts[:len(groupParts)-1]
+			} else {
+				groupParts = splitGroups(suspect.bottles, numTested)
+			}
+
+			split.numTested = len(groupParts)
+			splits = append(splits, split)
+			parts = append(parts, groupParts...)
+		}
+
+		results, err := lab.RunRound(parts)
+		if err != nil {
+			return nil, err
+		}
+
+		suspects = waiting
+		for _, split := range splits {
+			anyPositive := false
+			for i := split.first; i < split.first+split.numTested; i++ {
+				if results[i] {
+					anyPositive = true
+					addPositive(parts[i])
+				}
+			}
+
+			// The group held poison, so if no tested part did then the
+			// untested part must.
+			if split.untested != nil {
+				if anyPositive {
+					suspects = append(
+						suspects, suspectGroup{bottles: split.untested},
+					)
+				} else {
+					addPositive(split.untested)
+				}
+			}
+		}
+	}
+
+	sort.Ints(poisoned)
+	return poisoned, nil
+}
+
+// Return the smallest prime >= n.
+func nextPrime(n int) int {
+	if n <= 2 {
+		return 2
+	}
+
+	for ; ; n++ {
+		prime := true
+		for d := 2; d*d <= n; d++ {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+
+		if prime {
+			return n
+		}
+	}
+}
+
+// Return the smallest r such that r^m >= n.
+func intRoot(n, m int) int {
+	r := 1
+	for pow(r, m) < n {
+		r++
+	}
+
+	return r
+}
+
+// Return base^exp, saturating rather than overflowing.
+func pow(base, exp int) int {
+	result := 1
+	for i := 0; i < exp; i++ {
+		if result > (1<<62)/base {
+			return 1 << 62
+		}
+		result *= base
+	}
+
+	return result
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
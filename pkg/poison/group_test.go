@@ -0,0 +1,218 @@
+package poison
+
+import (
+	"testing"
+
+	"github.com/ryanc414/ctci/pkg/montecarlo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Find the poisoned bottles by brute force, testing each bottle on its own
+// strip.
+func bruteForce(t *testing.T, poisoned []bool) []int {
+	groups := make([][]int, len(poisoned))
+	for i := range groups {
+		groups[i] = []int{i}
+	}
+
+	results, err := NewLab(poisoned, len(poisoned)).RunRound(groups)
+	require.NoError(t, err)
+
+	var found []int
+	for i, positive := range results {
+		if positive {
+			found = append(found, i)
+		}
+	}
+
+	return found
+}
+
+// Call f with every subset of 0..n-1 with at most k members.
+func forEachSubset(n, k int, f func(subset []int)) {
+	var recurse func(start int, subset []int)
+	recurse = func(start int, subset []int) {
+		f(subset)
+		if len(subset) == k {
+			return
+		}
+
+		for i := start; i < n; i++ {
+			recurse(i+1, append(subset, i))
+		}
+	}
+
+	recurse(0, nil)
+}
+
+// Test each strategy against brute force, for every possible set of up to k
+// poisoned bottles.
+func TestMultiStrategiesExhaustive(t *testing.T) {
+	for _, numBottles := range []int{1, 2, 5, 13, 20} {
+		for k := 1; k <= 3; k++ {
+			strategies := []MultiStrategy{
+				Disjunct{K: k},
+				AdaptiveSplitting{K: k},
+				AdaptiveSplitting{},
+			}
+
+			forEachSubset(numBottles, k, func(subset []int) {
+				poisoned := make([]bool, numBottles)
+				for _, bottle := range subset {
+					poisoned[bottle] = true
+				}
+				expected := bruteForce(t, poisoned)
+
+				for _, strategy := range strategies {
+					found, err := strategy.FindPoisons(NewLab(poisoned, numBottles))
+					require.NoError(t, err, strategy.Name())
+					require.Equal(
+						t, expected, found, "%v, %v bottles", strategy.Name(), numBottles,
+					)
+				}
+			})
+		}
+	}
+}
+
+// Check by brute force that no bottle's strips are covered by any k others.
+func TestDisjunctPlanIsDisjunct(t *testing.T) {
+	for _, test := range []struct{ numBottles, k int }{
+		{30, 2},
+		{20, 3},
+		{50, 2},
+	} {
+		plan, err := DisjunctPlan(test.numBottles, test.k)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(plan), test.numBottles)
+
+		stripsFor := make([][]int, test.numBottles)
+		for strip, bottles := range plan {
+			for _, bottle := range bottles {
+				stripsFor[bottle] = append(stripsFor[bottle], strip)
+			}
+		}
+
+		for bottle := 0; bottle < test.numBottles; bottle++ {
+			forEachSubset(test.numBottles, test.k, func(others []int) {
+				if len(others) != test.k {
+					return
+				}
+
+				covered := make(map[int]bool)
+				for _, other := range others {
+					if other == bottle {
+						return
+					}
+					for _, strip := range stripsFor[other] {
+						covered[strip] = true
+					}
+				}
+
+				for _, strip := range stripsFor[bottle] {
+					if !covered[strip] {
+						return
+					}
+				}
+
+				t.Fatalf(
+					"bottle %v covered by %v (n=%v, k=%v)",
+					bottle,
+					others,
+					test.numBottles,
+					test.k,
+				)
+			})
+		}
+	}
+}
+
+func TestStripsForDisjunct(t *testing.T) {
+	assert.Equal(t, 0, StripsForDisjunct(1000, 0))
+	assert.Equal(t, 10, StripsForDisjunct(1000, 1))
+	assert.Equal(t, 10, StripsForDisjunct(1023, 1))
+	assert.Equal(t, 11, StripsForDisjunct(1024, 1))
+
+	// With as many poisoned bottles as bottles, every bottle must be tested
+	// on its own.
+	assert.Equal(t, 10, StripsForDisjunct(10, 10))
+
+	twoPoisoned := StripsForDisjunct(1000, 2)
+	assert.Greater(t, twoPoisoned, 10)
+	assert.Less(t, twoPoisoned, StripsForDisjunct(1000, 3))
+
+	_, err := DisjunctPlan(-1, 2)
+	assert.Error(t, err)
+}
+
+// Too many poisoned bottles for the design is reported, as long as it shows.
+func TestDisjunctTooManyPoisoned(t *testing.T) {
+	poisoned := make([]bool, 30)
+	for i := range poisoned {
+		poisoned[i] = true
+	}
+
+	_, err := Disjunct{K: 2}.FindPoisons(NewLab(poisoned, 30))
+	assert.Error(t, err)
+
+	_, err = Disjunct{K: 2}.FindPoisons(NewLab(poisoned, 2))
+	assert.ErrorIs(t, err, ErrNotEnoughStrips)
+}
+
+// Stopping once k bottles are found saves rounds.
+func TestAdaptiveSplittingStopsEarly(t *testing.T) {
+	poisoned := make([]bool, 1000)
+	poisoned[3] = true
+	poisoned[998] = true
+
+	known := NewLab(poisoned, 10)
+	found, err := AdaptiveSplitting{K: 2}.FindPoisons(known)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 998}, found)
+
+	unknown := NewLab(poisoned, 10)
+	found, err = AdaptiveSplitting{}.FindPoisons(unknown)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 998}, found)
+
+	assert.LessOrEqual(t, known.Rounds(), unknown.Rounds())
+}
+
+func TestAdaptiveSplittingRunsOut(t *testing.T) {
+	poisoned := make([]bool, 100)
+	for i := 0; i < 100; i += 10 {
+		poisoned[i] = true
+	}
+
+	_, err := AdaptiveSplitting{}.FindPoisons(NewLab(poisoned, 5))
+	assert.Equal(t, ErrNotEnoughStrips, err)
+}
+
+func TestRunMultiTrials(t *testing.T) {
+	config := montecarlo.Config{Trials: 50, Seed: 1}
+
+	stats, err := RunMultiTrials(Disjunct{K: 3}, 500, 500, 3, config)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, stats.MeanDays)
+
+	stats, err = RunMultiTrials(AdaptiveSplitting{K: 3}, 500, 40, 3, config)
+	require.NoError(t, err)
+	assert.Greater(t, stats.MeanDays, 7.0)
+
+	_, err = RunMultiTrials(AdaptiveSplitting{}, 5, 5, 6, config)
+	assert.Error(t, err)
+}
+
+func TestNextPrime(t *testing.T) {
+	for n, expected := range map[int]int{0: 2, 2: 2, 3: 3, 4: 5, 14: 17, 97: 97} {
+		assert.Equal(t, expected, nextPrime(n), n)
+	}
+}
+
+func TestIntRoot(t *testing.T) {
+	assert.Equal(t, 10, intRoot(1000, 3))
+	assert.Equal(t, 11, intRoot(1001, 3))
+	assert.Equal(t, 1000, intRoot(1000, 1))
+	assert.Equal(t, 1, intRoot(1, 5))
+}
@@ -0,0 +1,89 @@
+package poison
+
+import (
+	"errors"
+)
+
+// How long it takes for a test strip to show its result.
+const ResultDelayDays = 7
+
+var ErrNotEnoughStrips = errors.New("not enough test strips")
+
+// A Lab tests drops from bottles on strips. Each test takes ResultDelayDays
+// to give a result. Strips that test negative can be reused for later tests,
+// but a strip that tests positive is used up.
+type Lab struct {
+	poisoned   []bool
+	stripsLeft int
+	stripsUsed int
+	days       int
+	rounds     int
+}
+
+// Initialise a new lab with the given bottles, where poisoned[i] is true if
+// bottle i is poisoned, and the number of strips available.
+func NewLab(poisoned []bool, numStrips int) *Lab {
+	return &Lab{
+		poisoned:   poisoned,
+		stripsLeft: numStrips,
+	}
+}
+
+// Run a round of tests. Drops from each group of bottles go on their own
+// strip, and all strips are read together once the results are in. Returns
+// whether each strip tested positive.
+func (lab *Lab) RunRound(groups [][]int) ([]bool, error) {
+	if len(groups) > lab.stripsLeft {
+		return nil, ErrNotEnoughStrips
+	}
+
+	results := make([]bool, len(groups))
+	for i, group := range groups {
+		for _, bottle := range group {
+			if bottle < 0 || bottle >= len(lab.poisoned) {
+				return nil, errors.New("invalid bottle index")
+			}
+
+			if lab.poisoned[bottle] {
+				results[i] = true
+			}
+		}
+	}
+
+	for _, positive := range results {
+		if positive {
+			lab.stripsLeft--
+			lab.stripsUsed++
+		}
+	}
+
+	lab.days += ResultDelayDays
+	lab.rounds++
+
+	return results, nil
+}
+
+// Return the number of bottles being tested.
+func (lab *Lab) NumBottles() int {
+	return len(lab.poisoned)
+}
+
+// Return the number of strips that can still be used.
+func (lab *Lab) StripsLeft() int {
+	return lab.stripsLeft
+}
+
+// Return the number of strips used up by positive results.
+func (lab *Lab) StripsUsed() int {
+	return lab.stripsUsed
+}
+
+// Return the number of days spent waiting for results.
+func (lab *Lab) Days() int {
+	return lab.days
+}
+
+// Return the number of rounds of tests run.
+func (lab *Lab) Rounds() int {
+	return lab.rounds
+}
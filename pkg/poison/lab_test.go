@@ -0,0 +1,36 @@
+package poison
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLab(t *testing.T) {
+	poisoned := make([]bool, 8)
+	poisoned[5] = true
+	lab := NewLab(poisoned, 3)
+
+	results, err := lab.RunRound([][]int{{0, 1}, {4, 5}})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, results)
+	assert.Equal(t, 7, lab.Days())
+	assert.Equal(t, 1, lab.Rounds())
+	assert.Equal(t, 1, lab.StripsUsed())
+	assert.Equal(t, 2, lab.StripsLeft())
+
+	// The negative strip can be reused, but there are only two strips left.
+	_, err = lab.RunRound([][]int{{0}, {1}, {2}})
+	assert.Equal(t, ErrNotEnoughStrips, err)
+
+	results, err = lab.RunRound([][]int{{4}, {5}})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, results)
+	assert.Equal(t, 14, lab.Days())
+	assert.Equal(t, 2, lab.StripsUsed())
+	assert.Equal(t, 1, lab.StripsLeft())
+
+	_, err = lab.RunRound([][]int{{8}})
+	assert.Error(t, err)
+}
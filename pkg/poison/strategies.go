@@ -0,0 +1,175 @@
+package poison
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// A Strategy finds the single poisoned bottle by running tests in a lab.
+type Strategy interface {
+	Name() string
+	FindPoison(lab *Lab) (int, error)
+}
+
+// Return the number of strips needed to find one poisoned bottle among
+// numBottles in a single round, using the binary encoding.
+func StripsForBinaryEncoding(numBottles int) int {
+	if numBottles <= 1 {
+		return 0
+	}
+
+	return bits.Len(uint(numBottles - 1))
+}
+
+// Find the poisoned bottle in a single round. Each bottle's index is written
+// in binary, and a drop from the bottle goes on strip i if bit i of its index
+// is set. The positive strips then spell out the poisoned bottle's index.
+// This is as fast as possible, but uses up one strip per set bit.
+type BinaryEncoding struct{}
+
+func (BinaryEncoding) Name() string {
+	return "binary encoding"
+}
+
+func (BinaryEncoding) FindPoison(lab *Lab) (int, error) {
+	plan, err := DropPlan(lab.NumBottles(), lab.StripsLeft())
+	if err != nil {
+		return 0, err
+	}
+
+	if len(plan) == 0 {
+		return 0, nil
+	}
+
+	results, err := lab.RunRound(plan)
+	if err != nil {
+		return 0, err
+	}
+
+	return DecodeDropResults(results), nil
+}
+
+// Return the plan for finding one poisoned bottle among numBottles in a
+// single round using the binary encoding: plan[i] lists the bottles that get
+// a drop on strip i. Returns an error wrapping ErrNotEnoughStrips if
+// numStrips is too few.
+func DropPlan(numBottles, numStrips int) ([][]int, error) {
+	if numBottles < 1 {
+		return nil, errors.New("need at least one bottle")
+	}
+
+	needed := StripsForBinaryEncoding(numBottles)
+	if numStrips < needed {
+		return nil, fmt.Errorf(
+			"%w: %v bottles need %v strips, but only %v are available",
+			ErrNotEnoughStrips,
+			numBottles,
+			needed,
+			numStrips,
+		)
+	}
+
+	plan := make([][]int, needed)
+	for bottle := 0; bottle < numBottles; bottle++ {
+		for strip := range plan {
+			if bottle&(1<<strip) != 0 {
+				plan[strip] = append(plan[strip], bottle)
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// Return the poisoned bottle given which strips of a DropPlan tested
+// positive.
+func DecodeDropResults(results []bool) int {
+	bottle := 0
+	for strip, positive := range results {
+		if positive {
+			bottle |= 1 << strip
+		}
+	}
+
+	return bottle
+}
+
+// Find the poisoned bottle over several rounds. Each round the remaining
+// candidates are split into one more group than there are strips to test
+// with, and one group is tested on each strip. Only the group that tested
+// positive, or the untested group if none did, remains in the running. Each
+// round uses up at most one strip, so strips last longer than with the
+// binary encoding, but more rounds are needed.
+type Splitting struct {
+	// The most strips to use in each round. Zero means use every strip left.
+	StripsPerRound int
+}
+
+func (strategy Splitting) Name() string {
+	switch strategy.StripsPerRound {
+	case 0:
+		return "splitting (all strips)"
+
+	case 1:
+		return "splitting (1 strip per round)"
+	}
+
+	return fmt.Sprintf("splitting (%v strips per round)", strategy.StripsPerRound)
+}
+
+func (strategy Splitting) FindPoison(lab *Lab) (int, error) {
+	candidates := make([]int, lab.NumBottles())
+	for i := range candidates {
+		candidates[i] = i
+	}
+
+	for len(candidates) > 1 {
+		numStrips := lab.StripsLeft()
+		if strategy.StripsPerRound > 0 && strategy.StripsPerRound < numStrips {
+			numStrips = strategy.StripsPerRound
+		}
+		if numStrips == 0 {
+			return 0, ErrNotEnoughStrips
+		}
+
+		groups := splitGroups(candidates, numStrips+1)
+		tested := groups[:len(groups)-1]
+
+		results, err := lab.RunRound(tested)
+		if err != nil {
+			return 0, err
+		}
+
+		candidates = groups[len(groups)-1]
+		for i, positive := range results {
+			if positive {
+				candidates = tested[i]
+				break
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return 0, errors.New("no bottles to test")
+	}
+
+	return candidates[0], nil
+}
+
+// Split items into at most numGroups non-empty groups of nearly equal size.
+func splitGroups(items []int, numGroups int) [][]int {
+	if numGroups > len(items) {
+		numGroups = len(items)
+	}
+
+	groups := make([][]int, numGroups)
+	start := 0
+	for i := range groups {
+		size := (len(items) - start) / (numGroups - i)
+		groups[i] = items[start : start+size]
+		start += size
+	}
+
+	return groups
+}
@@ -0,0 +1,151 @@
+package poison
+
+import (
+	"testing"
+
+	"github.com/ryanc414/ctci/pkg/montecarlo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripsForBinaryEncoding(t *testing.T) {
+	assert.Equal(t, 0, StripsForBinaryEncoding(1))
+	assert.Equal(t, 1, StripsForBinaryEncoding(2))
+	assert.Equal(t, 2, StripsForBinaryEncoding(3))
+	assert.Equal(t, 10, StripsForBinaryEncoding(1000))
+	assert.Equal(t, 10, StripsForBinaryEncoding(1024))
+	assert.Equal(t, 11, StripsForBinaryEncoding(1025))
+}
+
+func TestSplitGroups(t *testing.T) {
+	items := []int{0, 1, 2, 3, 4, 5, 6}
+
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}, {4, 5, 6}}, splitGroups(items, 3))
+	assert.Equal(
+		t,
+		[][]int{{0}, {1}, {2}, {3}, {4}, {5}, {6}},
+		splitGroups(items, 10),
+	)
+}
+
+// Test that every strategy finds every possible poisoned bottle.
+func TestStrategies(t *testing.T) {
+	strategies := []Strategy{
+		BinaryEncoding{},
+		Splitting{},
+		Splitting{StripsPerRound: 1},
+		Splitting{StripsPerRound: 3},
+	}
+
+	for _, strategy := range strategies {
+		for bottle := 0; bottle < 100; bottle++ {
+			poisoned := make([]bool, 100)
+			poisoned[bottle] = true
+			lab := NewLab(poisoned, 10)
+
+			found, err := strategy.FindPoison(lab)
+			require.NoError(t, err, strategy.Name())
+			assert.Equal(t, bottle, found, strategy.Name())
+		}
+	}
+}
+
+func TestBinaryEncodingOneRound(t *testing.T) {
+	poisoned := make([]bool, 1000)
+	poisoned[0b1011] = true
+	lab := NewLab(poisoned, 10)
+
+	found, err := BinaryEncoding{}.FindPoison(lab)
+	require.NoError(t, err)
+	assert.Equal(t, 0b1011, found)
+	assert.Equal(t, 1, lab.Rounds())
+	assert.Equal(t, ResultDelayDays, lab.Days())
+	assert.Equal(t, 3, lab.StripsUsed())
+
+	_, err = BinaryEncoding{}.FindPoison(NewLab(poisoned, 9))
+	assert.ErrorIs(t, err, ErrNotEnoughStrips)
+}
+
+func TestDropPlan(t *testing.T) {
+	plan, err := DropPlan(5, 10)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{1, 3}, {2, 3}, {4}}, plan)
+
+	plan, err = DropPlan(1, 0)
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+
+	_, err = DropPlan(1025, 10)
+	assert.ErrorIs(t, err, ErrNotEnoughStrips)
+
+	_, err = DropPlan(0, 10)
+	assert.Error(t, err)
+
+	// Every bottle is identified by the strips it is dropped on.
+	plan, err = DropPlan(1024, 10)
+	require.NoError(t, err)
+	for bottle := 0; bottle < 1024; bottle++ {
+		results := make([]bool, len(plan))
+		for strip, bottles := range plan {
+			for _, b := range bottles {
+				if b == bottle {
+					results[strip] = true
+				}
+			}
+		}
+		assert.Equal(t, bottle, DecodeDropResults(results))
+	}
+}
+
+// Splitting with one strip per round is a binary search. A strip is only used
+// up when the tested half holds the poison.
+func TestSplittingBisection(t *testing.T) {
+	poisoned := make([]bool, 1024)
+	poisoned[700] = true
+	lab := NewLab(poisoned, 10)
+
+	found, err := Splitting{StripsPerRound: 1}.FindPoison(lab)
+	require.NoError(t, err)
+	assert.Equal(t, 700, found)
+	assert.Equal(t, 10, lab.Rounds())
+	assert.Equal(t, 70, lab.Days())
+	assert.Less(t, lab.StripsUsed(), lab.Rounds())
+}
+
+// With too few strips the splitting strategy runs out before it finds the
+// bottle.
+func TestSplittingRunsOut(t *testing.T) {
+	poisoned := make([]bool, 10)
+	poisoned[0] = true
+
+	_, err := Splitting{}.FindPoison(NewLab(poisoned, 1))
+	assert.Equal(t, ErrNotEnoughStrips, err)
+}
+
+func TestRunTrials(t *testing.T) {
+	config := montecarlo.Config{Trials: 500, Seed: 1, ChunkSize: 100}
+
+	stats, err := RunTrials(BinaryEncoding{}, 1000, 10, config)
+	require.NoError(t, err)
+	assert.Equal(t, 500, stats.Trials)
+	assert.Equal(t, 7.0, stats.MeanDays)
+	assert.InDelta(t, 7.0, stats.P90Days, 0.07)
+	assert.Equal(t, 7, stats.MaxDays)
+	assert.InDelta(t, 5, stats.MeanStripsUsed, 0.5)
+	assert.LessOrEqual(t, stats.MaxStripsUsed, 10)
+
+	stats, err = RunTrials(Splitting{}, 1000, 10, config)
+	require.NoError(t, err)
+	assert.Greater(t, stats.MeanDays, 7.0)
+	assert.GreaterOrEqual(t, stats.P90Days, stats.MeanDays)
+	assert.Less(t, stats.MeanStripsUsed, 3.0)
+	assert.LessOrEqual(t, stats.MaxStripsUsed, stats.MaxDays/ResultDelayDays)
+
+	config.Workers = 1
+	single, err := RunTrials(Splitting{}, 1000, 10, config)
+	require.NoError(t, err)
+	assert.Equal(t, stats, single)
+
+	_, err = RunTrials(Splitting{}, 1000, 1, config)
+	assert.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package poison
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+
+	"github.com/ryanc414/ctci/pkg/montecarlo"
+)
+
+// Summary of a strategy's performance over many trials.
+type TrialStats struct {
+	Strategy       string
+	Trials         int
+	MeanDays       float64
+	P90Days        float64
+	MaxDays        int
+	MeanStripsUsed float64
+	MaxStripsUsed  int
+}
+
+// Run a strategy many times against a lab with a randomly poisoned bottle,
+// checking that it finds the right bottle each time. The number of trials,
+// seed and parallelism are taken from the config.
+func RunTrials(
+	strategy Strategy, numBottles, numStrips int, config montecarlo.Config,
+) (TrialStats, error) {
+	return runTrials(
+		strategy.Name(),
+		numBottles,
+		numStrips,
+		1,
+		config,
+		func(lab *Lab) ([]int, error) {
+			found, err := strategy.FindPoison(lab)
+			return []int{found}, err
+		},
+	)
+}
+
+// Run a strategy many times against a lab with numPoisoned randomly poisoned
+// bottles, checking that it finds exactly the right bottles each time.
+func RunMultiTrials(
+	strategy MultiStrategy,
+	numBottles, numStrips, numPoisoned int,
+	config montecarlo.Config,
+) (TrialStats, error) {
+	return runTrials(
+		strategy.Name(),
+		numBottles,
+		numStrips,
+		numPoisoned,
+		config,
+		strategy.FindPoisons,
+	)
+}
+
+// Days taken and strips used up over a number of trials.
+type trialResults struct {
+	days   *montecarlo.Observations
+	strips *montecarlo.Observations
+}
+
+func newTrialResults() montecarlo.Accumulator {
+	return &trialResults{
+		days:   montecarlo.NewObservations(),
+		strips: montecarlo.NewObservations(),
+	}
+}
+
+// Add the results of other trials.
+func (results *trialResults) Merge(acc montecarlo.Accumulator) {
+	other := acc.(*trialResults)
+	results.days.Merge(other.days)
+	results.strips.Merge(other.strips)
+}
+
+// Run trials of a function that finds the poisoned bottles in a lab.
+func runTrials(
+	name string,
+	numBottles, numStrips, numPoisoned int,
+	config montecarlo.Config,
+	findPoisons func(lab *Lab) ([]int, error),
+) (TrialStats, error) {
+	if numPoisoned > numBottles {
+		return TrialStats{}, errors.New("more poisoned bottles than bottles")
+	}
+
+	acc, err := montecarlo.Run(
+		config,
+		newTrialResults,
+		func(rng *rand.Rand, acc montecarlo.Accumulator) error {
+			poisoned := make([]bool, numBottles)
+			actual := rng.Perm(numBottles)[:numPoisoned]
+			sort.Ints(actual)
+			for _, bottle := range actual {
+				poisoned[bottle] = true
+			}
+
+			lab := NewLab(poisoned, numStrips)
+			found, err := findPoisons(lab)
+			if err != nil {
+				return fmt.Errorf("%v: %w", name, err)
+			}
+
+			if !equalBottles(found, actual) {
+				return fmt.Errorf(
+					"%v: found bottles %v, expected %v", name, found, actual,
+				)
+			}
+
+			results := acc.(*trialResults)
+			results.days.Add(float64(lab.Days()))
+			results.strips.Add(float64(lab.StripsUsed()))
+			return nil
+		},
+	)
+	if err != nil {
+		return TrialStats{}, err
+	}
+
+	results := acc.(*trialResults)
+	return TrialStats{
+		Strategy:       name,
+		Trials:         int(results.days.Stats.Count()),
+		MeanDays:       results.days.Stats.Mean(),
+		P90Days:        results.days.Quantiles.Quantile(0.9),
+		MaxDays:        int(results.days.Stats.Max()),
+		MeanStripsUsed: results.strips.Stats.Mean(),
+		MaxStripsUsed:  int(results.strips.Stats.Max()),
+	}, nil
+}
+
+// Check whether two sorted lists of bottles are the same.
+func equalBottles(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Write a table comparing the stats for several strategies.
+func WriteTrialStats(w io.Writer, stats []TrialStats) error {
+	_, err := fmt.Fprintf(
+		w,
+		"%-32s %10s %9s %9s %12s %11s\n",
+		"Strategy",
+		"Mean days",
+		"P90 days",
+		"Max days",
+		"Mean strips",
+		"Max strips",
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range stats {
+		_, err = fmt.Fprintf(
+			w,
+			"%-32s %10.1f %9.0f %9d %12.2f %11d\n",
+			s.Strategy,
+			s.MeanDays,
+			s.P90Days,
+			s.MaxDays,
+			s.MeanStripsUsed,
+			s.MaxStripsUsed,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -207,7 +207,7 @@ func MultiplyRecur(a, b int) int {
 
 // Represent the Towers of Hanoi by an array of three stacks.
 type TowersOfHanoi struct {
-	stacks    [3]stacks.Stack
+	stacks    [3]*stacks.BasicStack[int]
 	numPieces int
 }
 
@@ -218,7 +218,7 @@ func InitTowersOfHanoi(numPieces int) TowersOfHanoi {
 
 	// Initialise three stacks.
 	for i := 0; i < 3; i++ {
-		towers.stacks[i] = stacks.NewBasicStack()
+		towers.stacks[i] = stacks.NewBasicStack[int]()
 	}
 
 	// Place pieces on the left stack.
@@ -260,7 +260,7 @@ func (towers TowersOfHanoi) movePiece(fromTower, toTower int) {
 
 	// Check that we are placing onto a larger piece, or the base.
 	onPiece, err := towers.stacks[toTower].Peek()
-	if err == nil && onPiece.(int) < piece.(int) {
+	if err == nil && onPiece < piece {
 		panic("Cannot move bigger piece onto smaller one.")
 	}
 
@@ -272,7 +272,7 @@ func (towers TowersOfHanoi) movePiece(fromTower, toTower int) {
 func (towers TowersOfHanoi) Display() {
 	maxTowerSize := 0
 	for i := range towers.stacks {
-		towerSize := len(towers.stacks[i].(*stacks.BasicStack).Data)
+		towerSize := len(towers.stacks[i].Data)
 		if towerSize > maxTowerSize {
 			maxTowerSize = towerSize
 		}
@@ -284,9 +284,9 @@ func (towers TowersOfHanoi) Display() {
 
 	for i := maxTowerSize - 1; i >= 0; i-- {
 		for j := range towers.stacks {
-			stackData := towers.stacks[j].(*stacks.BasicStack).Data
+			stackData := towers.stacks[j].Data
 			if i < len(stackData) {
-				builder.WriteString(renderPiece(stackData[i].(int)))
+				builder.WriteString(renderPiece(stackData[i]))
 			} else {
 				builder.WriteString(renderPiece(0))
 			}
@@ -200,7 +200,7 @@ func TestTowersOfHanoi(t *testing.T) {
 			break
 		}
 
-		if val.(int) != i {
+		if val != i {
 			t.Error(val)
 		}
 	}
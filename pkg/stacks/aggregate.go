@@ -0,0 +1,250 @@
+package stacks
+
+import (
+	"errors"
+
+	"golang.org/x/exp/constraints"
+)
+
+// An AggregateStack generalises MinStack to any associative way of combining
+// values - min, max, sum, gcd and so on. Each item is stored along with the
+// aggregate of itself and every item below it, so the aggregate of the whole
+// stack is always available in O(1) time.
+//
+// An AggregateStack must be constructed with NewAggregateStack, which sets its
+// combine function. MinStack and MaxStack, whose combine functions are fixed,
+// can be used from their zero values.
+type AggregateStack[T any] struct {
+	items   []aggregateItem[T]
+	combine func(a, b T) T
+}
+
+type aggregateItem[T any] struct {
+	value     T
+	aggregate T // combined value of this item and every item below it
+}
+
+// Construct a new empty stack, aggregated by an associative combine
+// function. Items are combined from the bottom of the stack upwards.
+func NewAggregateStack[T any](combine func(a, b T) T) *AggregateStack[T] {
+	return &AggregateStack[T]{combine: combine}
+}
+
+// Pop an item from the top of the stack.
+func (stack *AggregateStack[T]) Pop() (T, error) {
+	if stack.IsEmpty() {
+		var zero T
+		return zero, errors.New("Stack is empty")
+	}
+
+	newLen := len(stack.items) - 1
+	popped := stack.items[newLen].value
+	stack.items[newLen] = aggregateItem[T]{}
+	stack.items = stack.items[:newLen]
+
+	return popped, nil
+}
+
+// Push an item onto the stack.
+func (stack *AggregateStack[T]) Push(item T) {
+	if stack.combine == nil {
+		panic("AggregateStack has no combine function - use NewAggregateStack")
+	}
+
+	aggregate := item
+	if !stack.IsEmpty() {
+		aggregate = stack.combine(stack.items[len(stack.items)-1].aggregate, item)
+	}
+
+	stack.items = append(stack.items, aggregateItem[T]{
+		value:     item,
+		aggregate: aggregate,
+	})
+}
+
+// Peek at the top item in the stack.
+func (stack *AggregateStack[T]) Peek() (T, error) {
+	if stack.IsEmpty() {
+		var zero T
+		return zero, errors.New("Stack is empty")
+	}
+
+	return stack.items[len(stack.items)-1].value, nil
+}
+
+// Check if the stack is empty.
+func (stack *AggregateStack[T]) IsEmpty() bool {
+	return len(stack.items) == 0
+}
+
+// Return the number of items in the stack.
+func (stack *AggregateStack[T]) Len() int {
+	return len(stack.items)
+}
+
+// Return the aggregate of every item in the stack.
+func (stack *AggregateStack[T]) Aggregate() (T, error) {
+	if stack.IsEmpty() {
+		var zero T
+		return zero, errors.New("Stack is empty")
+	}
+
+	return stack.items[len(stack.items)-1].aggregate, nil
+}
+
+// A MaxStack is a stack with O(1) access to the maximum value in the stack.
+// Like MinStack, it is an AggregateStack whose zero value is an empty stack.
+type MaxStack[T constraints.Ordered] struct {
+	AggregateStack[T]
+}
+
+// Construct a new empty MaxStack.
+func NewMaxStack[T constraints.Ordered]() *MaxStack[T] {
+	return &MaxStack[T]{*NewAggregateStack(Max[T])}
+}
+
+// Push an item onto the stack.
+func (stack *MaxStack[T]) Push(item T) {
+	// A zero value MaxStack gets its combine function on the first push.
+	if stack.combine == nil {
+		stack.combine = Max[T]
+	}
+
+	stack.AggregateStack.Push(item)
+}
+
+// Return the maximum value in the stack.
+func (stack *MaxStack[T]) Max() (T, error) {
+	return stack.Aggregate()
+}
+
+// Combine functions for aggregate stacks and queues.
+
+// Return the lesser of two values.
+func Min[T constraints.Ordered](a, b T) T {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Return the greater of two values.
+func Max[T constraints.Ordered](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Return the sum of two values.
+func Sum[T constraints.Integer | constraints.Float | constraints.Complex](a, b T) T {
+	return a + b
+}
+
+// Return the greatest common divisor of two values, which is never
+// negative.
+func GCD[T constraints.Integer](a, b T) T {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// An AggregateQueue is a queue with O(1) access to the aggregate of every
+// item in it, in amortised O(1) time per operation. This makes it suitable
+// for sliding window queries: add each new item and remove the oldest, then
+// read off the aggregate of the window.
+//
+// Like myQueue, it is made of two stacks. Items are added to the in stack,
+// and moved to the out stack in reverse order when it runs out. The out
+// stack combines each item before those beneath it, so that both stacks
+// combine items in the order they were added, and the queue's aggregate is
+// the out stack's combined with the in stack's.
+type AggregateQueue[T any] struct {
+	inStack  *AggregateStack[T]
+	outStack *AggregateStack[T]
+	combine  func(a, b T) T
+}
+
+// Construct a new empty queue, aggregated by an associative combine
+// function. Items are combined from oldest to newest.
+func NewAggregateQueue[T any](combine func(a, b T) T) *AggregateQueue[T] {
+	return &AggregateQueue[T]{
+		inStack:  NewAggregateStack(combine),
+		outStack: NewAggregateStack(func(a, b T) T { return combine(b, a) }),
+		combine:  combine,
+	}
+}
+
+// Add an item onto the back of the queue.
+func (queue *AggregateQueue[T]) Add(item T) {
+	queue.inStack.Push(item)
+}
+
+// Remove an item from the front of the queue.
+func (queue *AggregateQueue[T]) Remove() (T, error) {
+	if queue.IsEmpty() {
+		var zero T
+		return zero, errors.New("Queue is empty")
+	}
+
+	queue.fillOutStack()
+	return queue.outStack.Pop()
+}
+
+// Peek at the front of the queue.
+func (queue *AggregateQueue[T]) Peek() (T, error) {
+	if queue.IsEmpty() {
+		var zero T
+		return zero, errors.New("Queue is empty")
+	}
+
+	queue.fillOutStack()
+	return queue.outStack.Peek()
+}
+
+// Check if the queue is empty.
+func (queue *AggregateQueue[T]) IsEmpty() bool {
+	return queue.inStack.IsEmpty() && queue.outStack.IsEmpty()
+}
+
+// Return the number of items in the queue.
+func (queue *AggregateQueue[T]) Len() int {
+	return queue.inStack.Len() + queue.outStack.Len()
+}
+
+// Return the aggregate of every item in the queue.
+func (queue *AggregateQueue[T]) Aggregate() (T, error) {
+	outAggregate, outErr := queue.outStack.Aggregate()
+	inAggregate, inErr := queue.inStack.Aggregate()
+
+	switch {
+	case outErr != nil && inErr != nil:
+		var zero T
+		return zero, errors.New("Queue is empty")
+
+	case outErr != nil:
+		return inAggregate, nil
+
+	case inErr != nil:
+		return outAggregate, nil
+
+	default:
+		return queue.combine(outAggregate, inAggregate), nil
+	}
+}
+
+// If the out stack is empty, move every item from the in stack onto it.
+func (queue *AggregateQueue[T]) fillOutStack() {
+	if !queue.outStack.IsEmpty() {
+		return
+	}
+
+	for !queue.inStack.IsEmpty() {
+		queue.outStack.Push(mustPop[T](queue.inStack))
+	}
+}
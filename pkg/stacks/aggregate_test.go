@@ -0,0 +1,243 @@
+package stacks
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test the AggregateStack type and methods.
+func TestAggregateStack(t *testing.T) {
+	genericStackTest(t, NewAggregateStack(Sum[int]))
+
+	stack := NewAggregateStack(Sum[int])
+	if _, err := stack.Aggregate(); err == nil {
+		t.Error("Expected error from empty stack")
+	}
+
+	for i := 1; i <= 4; i++ {
+		stack.Push(i)
+	}
+
+	for _, expected := range []int{10, 6, 3, 1} {
+		sum, err := stack.Aggregate()
+		if sum != expected || err != nil {
+			t.Error(sum, err)
+		}
+
+		if _, err := stack.Pop(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if !stack.IsEmpty() {
+		t.Error(stack.Len())
+	}
+}
+
+// Test that pushing onto an AggregateStack without a combine function fails
+// straight away, rather than on the second push.
+func TestAggregateStackZeroValue(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic")
+		}
+	}()
+
+	var stack AggregateStack[int]
+	stack.Push(1)
+}
+
+// Test that an AggregateStack combines items from the bottom upwards, using
+// string concatenation, which is associative but not commutative.
+func TestAggregateStackOrder(t *testing.T) {
+	stack := NewAggregateStack(func(a, b string) string { return a + b })
+	for _, s := range []string{"a", "b", "c"} {
+		stack.Push(s)
+	}
+
+	agg, err := stack.Aggregate()
+	if agg != "abc" || err != nil {
+		t.Error(agg, err)
+	}
+}
+
+// Test the MaxStack type and methods.
+func TestMaxStack(t *testing.T) {
+	genericStackTest(t, NewMaxStack[int]())
+
+	stack := NewMaxStack[int]()
+	if _, err := stack.Max(); err == nil {
+		t.Error("Expected error from empty stack")
+	}
+
+	items := []int{3, 1, 4, 1, 5, 9, 2, 6}
+	expectedMax := []int{3, 3, 4, 4, 5, 9, 9, 9}
+	for i, item := range items {
+		stack.Push(item)
+		max, err := stack.Max()
+		if max != expectedMax[i] || err != nil {
+			t.Error(i, max, err)
+		}
+	}
+
+	for i := len(items) - 1; i > 0; i-- {
+		if _, err := stack.Pop(); err != nil {
+			t.Error(err)
+		}
+
+		max, err := stack.Max()
+		if max != expectedMax[i-1] || err != nil {
+			t.Error(i, max, err)
+		}
+	}
+
+	// The zero value is an empty stack, as for MinStack.
+	var stringStack MaxStack[string]
+	stringStack.Push("apple")
+	stringStack.Push("pear")
+	stringStack.Push("fig")
+
+	max, err := stringStack.Max()
+	if max != "pear" || err != nil {
+		t.Error(max, err)
+	}
+}
+
+// Test the combine functions.
+func TestCombineFuncs(t *testing.T) {
+	if Min(2, 3) != 2 || Min("b", "a") != "a" {
+		t.Error("Min")
+	}
+
+	if Max(2, 3) != 3 || Max("b", "a") != "b" {
+		t.Error("Max")
+	}
+
+	if Sum(2, 3) != 5 || Sum(0.5, 0.25) != 0.75 {
+		t.Error("Sum")
+	}
+
+	gcdTests := []struct{ a, b, gcd int }{
+		{12, 18, 6},
+		{18, 12, 6},
+		{7, 13, 1},
+		{0, 5, 5},
+		{5, 0, 5},
+		{0, 0, 0},
+		{-12, 18, 6},
+		{12, -18, 6},
+	}
+	for _, test := range gcdTests {
+		if gcd := GCD(test.a, test.b); gcd != test.gcd {
+			t.Error(test, gcd)
+		}
+	}
+}
+
+// Test the AggregateQueue type and methods.
+func TestAggregateQueue(t *testing.T) {
+	genericQueueTest(t, NewAggregateQueue(Sum[int]))
+
+	queue := NewAggregateQueue(GCD[int])
+	if _, err := queue.Aggregate(); err == nil {
+		t.Error("Expected error from empty queue")
+	}
+
+	// Items are split between both stacks once one has been removed.
+	for _, item := range []int{7, 12, 18, 30} {
+		queue.Add(item)
+	}
+	checkAggregate(t, queue, 1)
+
+	if _, err := queue.Remove(); err != nil {
+		t.Error(err)
+	}
+	checkAggregate(t, queue, 6)
+
+	queue.Add(9)
+	checkAggregate(t, queue, 3)
+
+	for i := 0; i < 3; i++ {
+		if _, err := queue.Remove(); err != nil {
+			t.Error(err)
+		}
+	}
+	checkAggregate(t, queue, 9)
+
+	if queue.Len() != 1 {
+		t.Error(queue.Len())
+	}
+}
+
+// Test that an AggregateQueue combines items in the order they were added,
+// wherever they are in its stacks.
+func TestAggregateQueueOrder(t *testing.T) {
+	queue := NewAggregateQueue(func(a, b string) string { return a + b })
+	for _, s := range []string{"a", "b", "c"} {
+		queue.Add(s)
+	}
+
+	if _, err := queue.Remove(); err != nil {
+		t.Error(err)
+	}
+	queue.Add("d")
+	queue.Add("e")
+
+	checkAggregate(t, queue, "bcde")
+}
+
+// Test sliding window aggregates against brute force.
+func TestAggregateQueueSlidingWindow(t *testing.T) {
+	const numItems = 1000
+	const windowSize = 10
+
+	rng := rand.New(rand.NewSource(1))
+	items := make([]int, numItems)
+	for i := range items {
+		items[i] = rng.Intn(1000) - 500
+	}
+
+	combines := map[string]func(a, b int) int{
+		"min": Min[int],
+		"max": Max[int],
+		"sum": Sum[int],
+		"gcd": GCD[int],
+	}
+
+	for name, combine := range combines {
+		queue := NewAggregateQueue(combine)
+		for i, item := range items {
+			queue.Add(item)
+			if queue.Len() > windowSize {
+				if _, err := queue.Remove(); err != nil {
+					t.Fatal(name, err)
+				}
+			}
+
+			window := items[:i+1]
+			if len(window) > windowSize {
+				window = window[len(window)-windowSize:]
+			}
+
+			expected := window[0]
+			for _, windowItem := range window[1:] {
+				expected = combine(expected, windowItem)
+			}
+
+			agg, err := queue.Aggregate()
+			if agg != expected || err != nil {
+				t.Fatal(name, i, agg, expected, err)
+			}
+		}
+	}
+}
+
+// Check the aggregate of a queue.
+func checkAggregate[T comparable](
+	t *testing.T, queue *AggregateQueue[T], expected T,
+) {
+	agg, err := queue.Aggregate()
+	if agg != expected || err != nil {
+		t.Error(agg, err)
+	}
+}
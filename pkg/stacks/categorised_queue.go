@@ -0,0 +1,172 @@
+package stacks
+
+import (
+	"container/list"
+	"errors"
+	"sort"
+)
+
+// A FIFO queue whose entries each belong to a category. Entries can be
+// dequeued from a particular category, or whichever entry has been queued
+// longest regardless of category. Every entry gets an ID when it is queued,
+// which increases with each entry, so IDs also give the order in which
+// entries arrived.
+type CategorisedQueue[K comparable, V any] struct {
+	queues  map[K]*list.List         // entries for each category, oldest first
+	entries map[uint64]*list.Element // every entry, by ID
+	nextID  uint64
+}
+
+// An entry in a CategorisedQueue.
+type QueueEntry[K comparable, V any] struct {
+	ID       uint64
+	Category K
+	Value    V
+}
+
+// Construct a new empty categorised queue.
+func NewCategorisedQueue[K comparable, V any]() *CategorisedQueue[K, V] {
+	return &CategorisedQueue[K, V]{
+		queues:  make(map[K]*list.List),
+		entries: make(map[uint64]*list.Element),
+	}
+}
+
+// Add a value to the back of its category's queue, returning its ID.
+func (queue *CategorisedQueue[K, V]) Enqueue(category K, value V) uint64 {
+	entry := QueueEntry[K, V]{ID: queue.nextID, Category: category, Value: value}
+	queue.nextID++
+
+	categoryQueue, ok := queue.queues[category]
+	if !ok {
+		categoryQueue = list.New()
+		queue.queues[category] = categoryQueue
+	}
+	queue.entries[entry.ID] = categoryQueue.PushBack(entry)
+
+	return entry.ID
+}
+
+// Dequeue the entry that has been queued longest, from any category.
+func (queue *CategorisedQueue[K, V]) DequeueAny() (QueueEntry[K, V], error) {
+	oldest, err := queue.PeekAny()
+	if err != nil {
+		return oldest, err
+	}
+
+	return oldest, queue.Remove(oldest.ID)
+}
+
+// Dequeue the entry in a category that has been queued longest.
+func (queue *CategorisedQueue[K, V]) Dequeue(category K) (QueueEntry[K, V], error) {
+	entry, err := queue.Peek(category)
+	if err != nil {
+		return entry, err
+	}
+
+	return entry, queue.Remove(entry.ID)
+}
+
+// Peek at the entry that has been queued longest, from any category.
+func (queue *CategorisedQueue[K, V]) PeekAny() (QueueEntry[K, V], error) {
+	var oldest QueueEntry[K, V]
+	found := false
+
+	for _, categoryQueue := range queue.queues {
+		entry := categoryQueue.Front().Value.(QueueEntry[K, V])
+		if !found || entry.ID < oldest.ID {
+			oldest = entry
+			found = true
+		}
+	}
+
+	if !found {
+		return oldest, errors.New("Queue is empty")
+	}
+
+	return oldest, nil
+}
+
+// Peek at the entry in a category that has been queued longest.
+func (queue *CategorisedQueue[K, V]) Peek(category K) (QueueEntry[K, V], error) {
+	categoryQueue, ok := queue.queues[category]
+	if !ok {
+		return QueueEntry[K, V]{}, errors.New("No entries in category")
+	}
+
+	return categoryQueue.Front().Value.(QueueEntry[K, V]), nil
+}
+
+// Remove the entry with an ID from the queue, wherever it is.
+func (queue *CategorisedQueue[K, V]) Remove(id uint64) error {
+	element, ok := queue.entries[id]
+	if !ok {
+		return errors.New("No entry with ID")
+	}
+
+	category := element.Value.(QueueEntry[K, V]).Category
+	categoryQueue := queue.queues[category]
+	categoryQueue.Remove(element)
+	delete(queue.entries, id)
+
+	// Only categories with entries are kept, so that PeekAny doesn't have to
+	// skip over empty ones.
+	if categoryQueue.Len() == 0 {
+		delete(queue.queues, category)
+	}
+
+	return nil
+}
+
+// Return the entry with an ID, if it is still queued.
+func (queue *CategorisedQueue[K, V]) Get(id uint64) (QueueEntry[K, V], bool) {
+	element, ok := queue.entries[id]
+	if !ok {
+		return QueueEntry[K, V]{}, false
+	}
+
+	return element.Value.(QueueEntry[K, V]), true
+}
+
+// Return every entry, in the order they were queued.
+func (queue *CategorisedQueue[K, V]) Entries() []QueueEntry[K, V] {
+	entries := make([]QueueEntry[K, V], 0, len(queue.entries))
+	for _, element := range queue.entries {
+		entries = append(entries, element.Value.(QueueEntry[K, V]))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// Return the number of entries in a category.
+func (queue *CategorisedQueue[K, V]) Count(category K) int {
+	categoryQueue, ok := queue.queues[category]
+	if !ok {
+		return 0
+	}
+
+	return categoryQueue.Len()
+}
+
+// Return the number of entries in every category with any entries.
+func (queue *CategorisedQueue[K, V]) Counts() map[K]int {
+	counts := make(map[K]int, len(queue.queues))
+	for category, categoryQueue := range queue.queues {
+		counts[category] = categoryQueue.Len()
+	}
+
+	return counts
+}
+
+// Return the total number of entries.
+func (queue *CategorisedQueue[K, V]) Len() int {
+	return len(queue.entries)
+}
+
+// Check if the queue is empty.
+func (queue *CategorisedQueue[K, V]) IsEmpty() bool {
+	return queue.Len() == 0
+}
@@ -0,0 +1,101 @@
+package stacks
+
+import "testing"
+
+// Test dequeuing by category and in arrival order.
+func TestCategorisedQueue(t *testing.T) {
+	queue := NewCategorisedQueue[string, int]()
+
+	_, err := queue.DequeueAny()
+	if err == nil {
+		t.Error()
+	}
+
+	_, err = queue.Dequeue("a")
+	if err == nil {
+		t.Error()
+	}
+
+	for i, category := range []string{"a", "b", "a", "c", "b", "a"} {
+		queue.Enqueue(category, i)
+	}
+
+	if queue.Len() != 6 || queue.Count("a") != 3 || queue.Count("d") != 0 {
+		t.Error(queue.Counts())
+	}
+
+	counts := queue.Counts()
+	if len(counts) != 3 || counts["a"] != 3 || counts["b"] != 2 || counts["c"] != 1 {
+		t.Error(counts)
+	}
+
+	entry, err := queue.Dequeue("b")
+	if entry.Value != 1 || entry.Category != "b" || err != nil {
+		t.Error(entry, err)
+	}
+
+	entry, err = queue.PeekAny()
+	if entry.Value != 0 || err != nil {
+		t.Error(entry, err)
+	}
+
+	for _, expected := range []int{0, 2, 3, 4, 5} {
+		entry, err = queue.DequeueAny()
+		if entry.Value != expected || err != nil {
+			t.Error(entry, err)
+		}
+	}
+
+	if !queue.IsEmpty() || len(queue.Counts()) != 0 {
+		t.Error(queue.Counts())
+	}
+}
+
+// Test removing entries by ID.
+func TestCategorisedQueueRemove(t *testing.T) {
+	queue := NewCategorisedQueue[int, string]()
+	first := queue.Enqueue(1, "first")
+	second := queue.Enqueue(2, "second")
+	third := queue.Enqueue(1, "third")
+
+	if second <= first || third <= second {
+		t.Error(first, second, third)
+	}
+
+	entry, ok := queue.Get(third)
+	if !ok || entry.Value != "third" || entry.Category != 1 {
+		t.Error(entry, ok)
+	}
+
+	if err := queue.Remove(first); err != nil {
+		t.Error(err)
+	}
+
+	if err := queue.Remove(first); err == nil {
+		t.Error()
+	}
+
+	if _, ok := queue.Get(first); ok {
+		t.Error()
+	}
+
+	// Removing the only entry in a category leaves it empty.
+	if err := queue.Remove(second); err != nil {
+		t.Error(err)
+	}
+	if queue.Count(2) != 0 {
+		t.Error(queue.Count(2))
+	}
+	if _, err := queue.Peek(2); err == nil {
+		t.Error()
+	}
+
+	entry, err := queue.DequeueAny()
+	if entry.ID != third || err != nil {
+		t.Error(entry, err)
+	}
+
+	if !queue.IsEmpty() {
+		t.Error()
+	}
+}
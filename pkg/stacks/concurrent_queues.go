@@ -0,0 +1,290 @@
+package stacks
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// A bounded queue that is safe for concurrent use. Put blocks while the
+// queue is full and Take blocks while it is empty, until the context is
+// done. It also implements the Queue interface: Add blocks like Put, while
+// Remove and Peek return an error straight away if the queue is empty.
+type BlockingQueue[T any] struct {
+	mutex sync.Mutex
+	items []T // ring buffer
+	first int // index of the first item in the ring buffer
+	size  int
+
+	// Closed and replaced whenever an item is added or removed, to wake up
+	// any goroutines waiting for space or for an item.
+	changed chan struct{}
+}
+
+// Construct a new blocking queue that holds at most capacity items.
+func NewBlockingQueue[T any](capacity int) *BlockingQueue[T] {
+	if capacity < 1 {
+		panic("capacity must be >0")
+	}
+
+	return &BlockingQueue[T]{
+		items:   make([]T, capacity),
+		changed: make(chan struct{}),
+	}
+}
+
+// Add an item to the back of the queue, waiting for space if the queue is
+// full. Returns the context's error if it is done first.
+func (queue *BlockingQueue[T]) Put(ctx context.Context, item T) error {
+	for {
+		queue.mutex.Lock()
+		if queue.size < len(queue.items) {
+			queue.items[(queue.first+queue.size)%len(queue.items)] = item
+			queue.size++
+			queue.notify()
+			queue.mutex.Unlock()
+			return nil
+		}
+		changed := queue.changed
+		queue.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Remove an item from the front of the queue, waiting for one if the queue
+// is empty. Returns the context's error if it is done first.
+func (queue *BlockingQueue[T]) Take(ctx context.Context) (T, error) {
+	for {
+		queue.mutex.Lock()
+		if queue.size > 0 {
+			item := queue.pop()
+			queue.mutex.Unlock()
+			return item, nil
+		}
+		changed := queue.changed
+		queue.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// Add an item to the back of the queue, waiting for space if the queue is
+// full.
+func (queue *BlockingQueue[T]) Add(item T) {
+	if err := queue.Put(context.Background(), item); err != nil {
+		panic(err)
+	}
+}
+
+// Remove an item from the front of the queue without waiting.
+func (queue *BlockingQueue[T]) Remove() (T, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.size == 0 {
+		var zero T
+		return zero, errors.New("Queue is empty")
+	}
+
+	return queue.pop(), nil
+}
+
+// Peek at the front of the queue.
+func (queue *BlockingQueue[T]) Peek() (T, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.size == 0 {
+		var zero T
+		return zero, errors.New("Queue is empty")
+	}
+
+	return queue.items[queue.first], nil
+}
+
+// Check if the queue is empty.
+func (queue *BlockingQueue[T]) IsEmpty() bool {
+	return queue.Len() == 0
+}
+
+// Return the number of items in the queue.
+func (queue *BlockingQueue[T]) Len() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return queue.size
+}
+
+// Remove the front item. The mutex must be held and the queue not empty.
+func (queue *BlockingQueue[T]) pop() T {
+	item := queue.items[queue.first]
+
+	var zero T
+	queue.items[queue.first] = zero
+	queue.first = (queue.first + 1) % len(queue.items)
+	queue.size--
+	queue.notify()
+
+	return item
+}
+
+// Wake up all waiting goroutines. The mutex must be held.
+func (queue *BlockingQueue[T]) notify() {
+	close(queue.changed)
+	queue.changed = make(chan struct{})
+}
+
+// A bounded multi-producer, multi-consumer queue that is safe for concurrent
+// use without locks, after Dmitry Vyukov's design. Items are stored in a
+// ring of cells, each with a sequence number that says whether it is ready
+// to be written or read for a given position in the queue. Producers and
+// consumers claim positions by compare-and-swap, so they only contend with
+// each other when they race for the same position.
+type LockFreeQueue[T any] struct {
+	// The next positions to write to and read from. Kept on separate cache
+	// lines so that producers and consumers don't slow each other down.
+	enqueuePos uint64
+	_          [56]byte
+	dequeuePos uint64
+	_          [56]byte
+
+	mask  uint64
+	cells []lockFreeCell[T]
+}
+
+type lockFreeCell[T any] struct {
+	// For position pos in the queue, the cell at pos&mask has sequence pos
+	// when it is free to write, and pos+1 when it holds an item to read.
+	sequence uint64
+	item     T
+}
+
+// Construct a new lock-free queue. Its capacity is rounded up to a power of
+// two, and is at least two: with a single cell, the sequence number of a
+// full cell would look free to the next producer.
+func NewLockFreeQueue[T any](capacity int) *LockFreeQueue[T] {
+	if capacity < 1 {
+		panic("capacity must be >0")
+	}
+
+	size := 2
+	for size < capacity {
+		size *= 2
+	}
+
+	cells := make([]lockFreeCell[T], size)
+	for i := range cells {
+		cells[i].sequence = uint64(i)
+	}
+
+	return &LockFreeQueue[T]{mask: uint64(size - 1), cells: cells}
+}
+
+// Add an item to the back of the queue if there is space. Returns whether
+// the item was added.
+func (queue *LockFreeQueue[T]) TryAdd(item T) bool {
+	pos := atomic.LoadUint64(&queue.enqueuePos)
+	for {
+		cell := &queue.cells[pos&queue.mask]
+		seq := atomic.LoadUint64(&cell.sequence)
+
+		switch diff := int64(seq - pos); {
+		case diff == 0:
+			if atomic.CompareAndSwapUint64(&queue.enqueuePos, pos, pos+1) {
+				cell.item = item
+				atomic.StoreUint64(&cell.sequence, pos+1)
+				return true
+			}
+
+		case diff < 0:
+			// The cell still holds the item from one lap ago.
+			return false
+		}
+
+		pos = atomic.LoadUint64(&queue.enqueuePos)
+	}
+}
+
+// Remove an item from the front of the queue if there is one. Returns
+// whether an item was removed.
+func (queue *LockFreeQueue[T]) TryRemove() (T, bool) {
+	pos := atomic.LoadUint64(&queue.dequeuePos)
+	for {
+		cell := &queue.cells[pos&queue.mask]
+		seq := atomic.LoadUint64(&cell.sequence)
+
+		switch diff := int64(seq - (pos + 1)); {
+		case diff == 0:
+			if atomic.CompareAndSwapUint64(&queue.dequeuePos, pos, pos+1) {
+				item := cell.item
+				var zero T
+				cell.item = zero
+				atomic.StoreUint64(&cell.sequence, pos+queue.mask+1)
+				return item, true
+			}
+
+		case diff < 0:
+			// Nothing has been written to the cell for this position yet.
+			var zero T
+			return zero, false
+		}
+
+		pos = atomic.LoadUint64(&queue.dequeuePos)
+	}
+}
+
+// Add an item to the back of the queue, yielding to other goroutines until
+// there is space.
+func (queue *LockFreeQueue[T]) Add(item T) {
+	for !queue.TryAdd(item) {
+		runtime.Gosched()
+	}
+}
+
+// Remove an item from the front of the queue without waiting.
+func (queue *LockFreeQueue[T]) Remove() (T, error) {
+	item, ok := queue.TryRemove()
+	if !ok {
+		return item, errors.New("Queue is empty")
+	}
+
+	return item, nil
+}
+
+// Peek at the front of the queue. Other goroutines may remove the item at
+// any time, so only use this when there are no concurrent consumers.
+func (queue *LockFreeQueue[T]) Peek() (T, error) {
+	pos := atomic.LoadUint64(&queue.dequeuePos)
+	cell := &queue.cells[pos&queue.mask]
+	if atomic.LoadUint64(&cell.sequence) != pos+1 {
+		var zero T
+		return zero, errors.New("Queue is empty")
+	}
+
+	return cell.item, nil
+}
+
+// Check if the queue is empty. With concurrent producers and consumers the
+// answer may be out of date as soon as it is returned.
+func (queue *LockFreeQueue[T]) IsEmpty() bool {
+	pos := atomic.LoadUint64(&queue.dequeuePos)
+	cell := &queue.cells[pos&queue.mask]
+	return atomic.LoadUint64(&cell.sequence) != pos+1
+}
+
+// Return the most items the queue can hold.
+func (queue *LockFreeQueue[T]) Capacity() int {
+	return len(queue.cells)
+}
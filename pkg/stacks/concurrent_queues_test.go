@@ -0,0 +1,229 @@
+package stacks
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test the BlockingQueue type and methods.
+func TestBlockingQueue(t *testing.T) {
+	queue := NewBlockingQueue[int](3)
+	genericQueueTest(t, queue)
+}
+
+// Test that Put and Take wait until they can go ahead, or the context is
+// done.
+func TestBlockingQueueWaits(t *testing.T) {
+	queue := NewBlockingQueue[int](1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := queue.Take(ctx)
+	if err != context.DeadlineExceeded {
+		t.Error(err)
+	}
+
+	if err := queue.Put(context.Background(), 1); err != nil {
+		t.Error(err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := queue.Put(ctx, 2); err != context.DeadlineExceeded {
+		t.Error(err)
+	}
+
+	// A blocked Put goes ahead once an item is taken.
+	done := make(chan error)
+	go func() {
+		done <- queue.Put(context.Background(), 3)
+	}()
+
+	val, err := queue.Take(context.Background())
+	if val != 1 || err != nil {
+		t.Error(val, err)
+	}
+
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+
+	val, err = queue.Take(context.Background())
+	if val != 3 || err != nil {
+		t.Error(val, err)
+	}
+}
+
+// Test the LockFreeQueue type and methods.
+func TestLockFreeQueue(t *testing.T) {
+	queue := NewLockFreeQueue[int](3)
+	genericQueueTest(t, queue)
+
+	if queue.Capacity() != 4 {
+		t.Error(queue.Capacity())
+	}
+
+	for i := 0; i < 4; i++ {
+		if !queue.TryAdd(i) {
+			t.Error(i)
+		}
+	}
+
+	if queue.TryAdd(4) {
+		t.Error("added to a full queue")
+	}
+
+	// Go round the ring a few times.
+	for i := 4; i < 20; i++ {
+		val, ok := queue.TryRemove()
+		if val != i-4 || !ok {
+			t.Error(val, ok)
+		}
+
+		if !queue.TryAdd(i) {
+			t.Error(i)
+		}
+	}
+}
+
+// Test that the smallest lock-free queue still stops adding items when
+// full.
+func TestLockFreeQueueCapacityOne(t *testing.T) {
+	queue := NewLockFreeQueue[int](1)
+	if queue.Capacity() != 2 {
+		t.Error(queue.Capacity())
+	}
+
+	for i := 0; i < queue.Capacity(); i++ {
+		if !queue.TryAdd(i) {
+			t.Error(i)
+		}
+	}
+
+	if queue.TryAdd(2) {
+		t.Error("added to a full queue")
+	}
+
+	for i := 0; i < queue.Capacity(); i++ {
+		val, ok := queue.TryRemove()
+		if val != i || !ok {
+			t.Error(val, ok)
+		}
+	}
+
+	if _, ok := queue.TryRemove(); ok {
+		t.Error("removed from an empty queue")
+	}
+}
+
+// Run producers and consumers against a queue at the same time, checking
+// that every item comes out exactly once and that items from each producer
+// come out in the order they went in.
+func concurrentQueueTest(
+	t *testing.T, put func(item [2]int), take func() [2]int,
+) {
+	const numProducers = 4
+	const numConsumers = 4
+	const itemsPerProducer = 5000
+
+	var wg sync.WaitGroup
+	for producer := 0; producer < numProducers; producer++ {
+		wg.Add(1)
+		go func(producer int) {
+			defer wg.Done()
+			for i := 0; i < itemsPerProducer; i++ {
+				put([2]int{producer, i})
+			}
+		}(producer)
+	}
+
+	taken := make([][][2]int, numConsumers)
+	for consumer := 0; consumer < numConsumers; consumer++ {
+		wg.Add(1)
+		go func(consumer int) {
+			defer wg.Done()
+			for i := 0; i < numProducers*itemsPerProducer/numConsumers; i++ {
+				taken[consumer] = append(taken[consumer], take())
+			}
+		}(consumer)
+	}
+
+	wg.Wait()
+
+	var seen [numProducers][itemsPerProducer]bool
+	for _, items := range taken {
+		last := [numProducers]int{-1, -1, -1, -1}
+		for _, item := range items {
+			producer, i := item[0], item[1]
+			if seen[producer][i] {
+				t.Fatalf("item %v taken twice", item)
+			}
+			seen[producer][i] = true
+
+			if i <= last[producer] {
+				t.Fatalf("item %v taken after %v", item, last[producer])
+			}
+			last[producer] = i
+		}
+	}
+
+	for producer := range seen {
+		for i, ok := range seen[producer] {
+			if !ok {
+				t.Fatalf("item %v not taken", [2]int{producer, i})
+			}
+		}
+	}
+}
+
+// Stress test the blocking queue - run with -race.
+func TestBlockingQueueConcurrent(t *testing.T) {
+	queue := NewBlockingQueue[[2]int](16)
+	ctx := context.Background()
+
+	concurrentQueueTest(
+		t,
+		func(item [2]int) {
+			if err := queue.Put(ctx, item); err != nil {
+				t.Error(err)
+			}
+		},
+		func() [2]int {
+			item, err := queue.Take(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			return item
+		},
+	)
+
+	if !queue.IsEmpty() {
+		t.Error(queue.Len())
+	}
+}
+
+// Stress test the lock-free queue - run with -race.
+func TestLockFreeQueueConcurrent(t *testing.T) {
+	queue := NewLockFreeQueue[[2]int](16)
+
+	concurrentQueueTest(
+		t,
+		queue.Add,
+		func() [2]int {
+			for {
+				item, err := queue.Remove()
+				if err == nil {
+					return item
+				}
+				runtime.Gosched()
+			}
+		},
+	)
+
+	if !queue.IsEmpty() {
+		t.Error()
+	}
+}
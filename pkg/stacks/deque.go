@@ -0,0 +1,135 @@
+package stacks
+
+import "errors"
+
+// A double-ended queue, implemented as a ring buffer that doubles in size
+// when full. Items can be pushed and popped at either end in amortised O(1)
+// time. As a Queue, items are added at the back and removed from the front.
+type Deque[T any] struct {
+	items []T
+	first int // index of the front item in the ring buffer
+	size  int
+}
+
+// Construct a new empty deque.
+func NewDeque[T any]() *Deque[T] {
+	return &Deque[T]{}
+}
+
+// Add an item to the front of the deque.
+func (deque *Deque[T]) PushFront(item T) {
+	deque.growIfFull()
+
+	deque.first = (deque.first - 1 + len(deque.items)) % len(deque.items)
+	deque.items[deque.first] = item
+	deque.size++
+}
+
+// Add an item to the back of the deque.
+func (deque *Deque[T]) PushBack(item T) {
+	deque.growIfFull()
+
+	deque.items[deque.index(deque.size)] = item
+	deque.size++
+}
+
+// Remove and return the item at the front of the deque.
+func (deque *Deque[T]) PopFront() (T, error) {
+	var zero T
+	if deque.IsEmpty() {
+		return zero, errors.New("Deque is empty")
+	}
+
+	item := deque.items[deque.first]
+	deque.items[deque.first] = zero
+	deque.first = deque.index(1)
+	deque.size--
+
+	return item, nil
+}
+
+// Remove and return the item at the back of the deque.
+func (deque *Deque[T]) PopBack() (T, error) {
+	var zero T
+	if deque.IsEmpty() {
+		return zero, errors.New("Deque is empty")
+	}
+
+	backIx := deque.index(deque.size - 1)
+	item := deque.items[backIx]
+	deque.items[backIx] = zero
+	deque.size--
+
+	return item, nil
+}
+
+// Peek at the item at the front of the deque.
+func (deque *Deque[T]) Front() (T, error) {
+	if deque.IsEmpty() {
+		var zero T
+		return zero, errors.New("Deque is empty")
+	}
+
+	return deque.items[deque.first], nil
+}
+
+// Peek at the item at the back of the deque.
+func (deque *Deque[T]) Back() (T, error) {
+	if deque.IsEmpty() {
+		var zero T
+		return zero, errors.New("Deque is empty")
+	}
+
+	return deque.items[deque.index(deque.size-1)], nil
+}
+
+// Add an item to the back of the deque.
+func (deque *Deque[T]) Add(item T) {
+	deque.PushBack(item)
+}
+
+// Remove an item from the front of the deque.
+func (deque *Deque[T]) Remove() (T, error) {
+	return deque.PopFront()
+}
+
+// Peek at the front of the deque.
+func (deque *Deque[T]) Peek() (T, error) {
+	return deque.Front()
+}
+
+// Check if the deque is empty.
+func (deque *Deque[T]) IsEmpty() bool {
+	return deque.size == 0
+}
+
+// Return the number of items in the deque.
+func (deque *Deque[T]) Len() int {
+	return deque.size
+}
+
+// Return the index in the ring buffer of the item offset places from the
+// front.
+func (deque *Deque[T]) index(offset int) int {
+	return (deque.first + offset) % len(deque.items)
+}
+
+// Double the size of the ring buffer if it is full, moving the items to the
+// start of the new buffer.
+func (deque *Deque[T]) growIfFull() {
+	if deque.size < len(deque.items) {
+		return
+	}
+
+	newLen := 2 * len(deque.items)
+	if newLen == 0 {
+		newLen = 4
+	}
+
+	items := make([]T, newLen)
+	numCopied := copy(items, deque.items[deque.first:])
+	copy(items[numCopied:], deque.items[:deque.first])
+
+	deque.items = items
+	deque.first = 0
+}
@@ -0,0 +1,116 @@
+package stacks
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test the Deque type against the Queue interface.
+func TestDeque(t *testing.T) {
+	deque := NewDeque[int]()
+	genericQueueTest(t, deque)
+}
+
+// Test pushing and popping at both ends.
+func TestDequeEnds(t *testing.T) {
+	deque := NewDeque[int]()
+
+	_, err := deque.PopBack()
+	if err == nil {
+		t.Error()
+	}
+
+	_, err = deque.Back()
+	if err == nil {
+		t.Error()
+	}
+
+	// Builds 3 2 1 4 5 6, wrapping round the ring buffer.
+	deque.PushBack(4)
+	deque.PushFront(1)
+	deque.PushFront(2)
+	deque.PushBack(5)
+	deque.PushFront(3)
+	deque.PushBack(6)
+
+	if deque.Len() != 6 {
+		t.Error(deque.Len())
+	}
+
+	val, err := deque.Front()
+	if val != 3 || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = deque.Back()
+	if val != 6 || err != nil {
+		t.Error(val, err)
+	}
+
+	for _, expected := range []int{6, 5, 4} {
+		val, err = deque.PopBack()
+		if val != expected || err != nil {
+			t.Error(val, err)
+		}
+	}
+
+	for _, expected := range []int{3, 2, 1} {
+		val, err = deque.PopFront()
+		if val != expected || err != nil {
+			t.Error(val, err)
+		}
+	}
+
+	if !deque.IsEmpty() {
+		t.Error()
+	}
+}
+
+// Test random operations against a slice.
+func TestDequeRandom(t *testing.T) {
+	deque := NewDeque[int]()
+	var expected []int
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		switch rng.Intn(5) {
+		case 0:
+			deque.PushFront(i)
+			expected = append([]int{i}, expected...)
+
+		case 1, 2:
+			deque.PushBack(i)
+			expected = append(expected, i)
+
+		case 3:
+			val, err := deque.PopFront()
+			if len(expected) == 0 {
+				if err == nil {
+					t.Fatal("popped from empty deque")
+				}
+				continue
+			}
+			if val != expected[0] || err != nil {
+				t.Fatal(val, err)
+			}
+			expected = expected[1:]
+
+		case 4:
+			val, err := deque.PopBack()
+			if len(expected) == 0 {
+				if err == nil {
+					t.Fatal("popped from empty deque")
+				}
+				continue
+			}
+			if val != expected[len(expected)-1] || err != nil {
+				t.Fatal(val, err)
+			}
+			expected = expected[:len(expected)-1]
+		}
+
+		if deque.Len() != len(expected) {
+			t.Fatal(deque.Len(), len(expected))
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package stacks
+
+import (
+	"container/heap"
+	"errors"
+
+	"golang.org/x/exp/constraints"
+)
+
+// A priority queue, implemented as a binary heap. Items come out in the
+// order given by a comparator, and items that compare equal come out in the
+// order they were added. Pushing an item returns a handle, which can be used
+// to change its priority or delete it later.
+type PriorityQueue[T any] struct {
+	heap priorityHeap[T]
+}
+
+// A handle to an item in a priority queue.
+type PQHandle[T any] struct {
+	item  T
+	index int   // position in the heap, or -1 once the item has left the queue
+	seq   int64 // order in which the item was added, to break ties
+}
+
+// Return the item the handle refers to.
+func (handle *PQHandle[T]) Item() T {
+	return handle.item
+}
+
+// Construct a new priority queue. Items for which less returns true come out
+// first.
+func NewPriorityQueue[T any](less func(a, b T) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{heap: priorityHeap[T]{less: less}}
+}
+
+// Comparator for a priority queue whose smallest item comes out first.
+func Less[T constraints.Ordered](a, b T) bool {
+	return a < b
+}
+
+// Comparator for a priority queue whose largest item comes out first.
+func Greater[T constraints.Ordered](a, b T) bool {
+	return a > b
+}
+
+// Add an item to the queue, returning a handle to it.
+func (queue *PriorityQueue[T]) Push(item T) *PQHandle[T] {
+	handle := &PQHandle[T]{item: item, seq: queue.heap.nextSeq}
+	queue.heap.nextSeq++
+	heap.Push(&queue.heap, handle)
+
+	return handle
+}
+
+// Remove and return the first item in the queue.
+func (queue *PriorityQueue[T]) Pop() (T, error) {
+	if queue.IsEmpty() {
+		var zero T
+		return zero, errors.New("Queue is empty")
+	}
+
+	return heap.Pop(&queue.heap).(*PQHandle[T]).item, nil
+}
+
+// Replace the item a handle refers to, moving it to its new place in the
+// queue. Use this to decrease (or increase) an item's priority. Returns an
+// error if the item has already left the queue.
+func (queue *PriorityQueue[T]) Update(handle *PQHandle[T], item T) error {
+	if !queue.contains(handle) {
+		return errors.New("Item is not in the queue")
+	}
+
+	handle.item = item
+	heap.Fix(&queue.heap, handle.index)
+	return nil
+}
+
+// Delete the item a handle refers to from the queue. Returns an error if the
+// item has already left the queue.
+func (queue *PriorityQueue[T]) Delete(handle *PQHandle[T]) error {
+	if !queue.contains(handle) {
+		return errors.New("Item is not in the queue")
+	}
+
+	heap.Remove(&queue.heap, handle.index)
+	return nil
+}
+
+// Add an item to the queue.
+func (queue *PriorityQueue[T]) Add(item T) {
+	queue.Push(item)
+}
+
+// Remove and return the first item in the queue.
+func (queue *PriorityQueue[T]) Remove() (T, error) {
+	return queue.Pop()
+}
+
+// Peek at the first item in the queue.
+func (queue *PriorityQueue[T]) Peek() (T, error) {
+	if queue.IsEmpty() {
+		var zero T
+		return zero, errors.New("Queue is empty")
+	}
+
+	return queue.heap.handles[0].item, nil
+}
+
+// Check if the queue is empty.
+func (queue *PriorityQueue[T]) IsEmpty() bool {
+	return queue.Len() == 0
+}
+
+// Return the number of items in the queue.
+func (queue *PriorityQueue[T]) Len() int {
+	return len(queue.heap.handles)
+}
+
+// Check if a handle refers to an item in this queue.
+func (queue *PriorityQueue[T]) contains(handle *PQHandle[T]) bool {
+	return handle.index >= 0 &&
+		handle.index < len(queue.heap.handles) &&
+		queue.heap.handles[handle.index] == handle
+}
+
+// Implements heap.Interface, keeping each handle's index up to date.
+type priorityHeap[T any] struct {
+	handles []*PQHandle[T]
+	less    func(a, b T) bool
+	nextSeq int64
+}
+
+func (h priorityHeap[T]) Len() int {
+	return len(h.handles)
+}
+
+func (h priorityHeap[T]) Less(i, j int) bool {
+	a, b := h.handles[i], h.handles[j]
+	if h.less(a.item, b.item) {
+		return true
+	}
+	if h.less(b.item, a.item) {
+		return false
+	}
+
+	return a.seq < b.seq
+}
+
+func (h priorityHeap[T]) Swap(i, j int) {
+	h.handles[i], h.handles[j] = h.handles[j], h.handles[i]
+	h.handles[i].index = i
+	h.handles[j].index = j
+}
+
+func (h *priorityHeap[T]) Push(x interface{}) {
+	handle := x.(*PQHandle[T])
+	handle.index = len(h.handles)
+	h.handles = append(h.handles, handle)
+}
+
+func (h *priorityHeap[T]) Pop() interface{} {
+	last := len(h.handles) - 1
+	handle := h.handles[last]
+	h.handles[last] = nil
+	h.handles = h.handles[:last]
+	handle.index = -1
+
+	return handle
+}
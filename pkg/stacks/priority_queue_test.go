@@ -0,0 +1,169 @@
+package stacks
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Test the PriorityQueue type against the Queue interface. Items added in
+// increasing order come out in the same order.
+func TestPriorityQueue(t *testing.T) {
+	queue := NewPriorityQueue(Less[int])
+	genericQueueTest(t, queue)
+}
+
+// Test that items come out in priority order, with ties in the order they
+// were added.
+func TestPriorityQueueOrder(t *testing.T) {
+	type task struct {
+		name     string
+		priority int
+	}
+
+	queue := NewPriorityQueue(func(a, b task) bool {
+		return a.priority > b.priority
+	})
+	for _, item := range []task{
+		{"a", 1}, {"b", 3}, {"c", 2}, {"d", 3}, {"e", 1}, {"f", 2},
+	} {
+		queue.Push(item)
+	}
+
+	for _, expected := range []string{"b", "d", "c", "f", "a", "e"} {
+		item, err := queue.Pop()
+		if item.name != expected || err != nil {
+			t.Error(item, err)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	maxQueue := NewPriorityQueue(Greater[float64])
+	var values []float64
+	for i := 0; i < 1000; i++ {
+		value := rng.Float64()
+		values = append(values, value)
+		maxQueue.Add(value)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(values)))
+
+	for _, expected := range values {
+		value, err := maxQueue.Remove()
+		if value != expected || err != nil {
+			t.Fatal(value, err)
+		}
+	}
+}
+
+// Test changing the priority of items and deleting them through their
+// handles.
+func TestPriorityQueueHandles(t *testing.T) {
+	queue := NewPriorityQueue(Less[int])
+	handles := make(map[int]*PQHandle[int])
+	for _, value := range []int{50, 40, 30, 20, 10} {
+		handles[value] = queue.Push(value)
+	}
+
+	// Decrease 50 to 5, so it comes out first.
+	if err := queue.Update(handles[50], 5); err != nil {
+		t.Error(err)
+	}
+	if handles[50].Item() != 5 {
+		t.Error(handles[50].Item())
+	}
+
+	// Increase 10 to 35.
+	if err := queue.Update(handles[10], 35); err != nil {
+		t.Error(err)
+	}
+
+	if err := queue.Delete(handles[30]); err != nil {
+		t.Error(err)
+	}
+
+	if queue.Len() != 4 {
+		t.Error(queue.Len())
+	}
+
+	for _, expected := range []int{5, 20, 35, 40} {
+		value, err := queue.Pop()
+		if value != expected || err != nil {
+			t.Error(value, err)
+		}
+	}
+
+	// Handles to items that have left the queue are rejected, even once
+	// other items take their place in the heap.
+	queue.Push(1)
+	if err := queue.Update(handles[20], 0); err == nil {
+		t.Error()
+	}
+	if err := queue.Delete(handles[30]); err == nil {
+		t.Error()
+	}
+
+	other := NewPriorityQueue(Less[int])
+	if err := other.Delete(queue.Push(2)); err == nil {
+		t.Error()
+	}
+}
+
+// Test a priority queue as used by Dijkstra's algorithm, decreasing the
+// distance to each node as shorter paths are found.
+func TestPriorityQueueDijkstra(t *testing.T) {
+	type node struct {
+		id       int
+		distance int
+	}
+
+	// Edges of a small weighted graph, and the shortest distances from node
+	// 0.
+	edges := map[int]map[int]int{
+		0: {1: 4, 2: 1},
+		1: {3: 1},
+		2: {1: 2, 3: 5},
+		3: {4: 3},
+	}
+	expected := []int{0, 3, 1, 4, 7}
+
+	const unreached = 1 << 30
+	queue := NewPriorityQueue(func(a, b node) bool {
+		return a.distance < b.distance
+	})
+
+	handles := make([]*PQHandle[node], len(expected))
+	for id := range handles {
+		distance := unreached
+		if id == 0 {
+			distance = 0
+		}
+		handles[id] = queue.Push(node{id, distance})
+	}
+
+	distances := make([]int, len(expected))
+	for !queue.IsEmpty() {
+		current, err := queue.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		distances[current.id] = current.distance
+
+		for next, weight := range edges[current.id] {
+			handle := handles[next]
+			distance := current.distance + weight
+			if distance < handle.Item().distance {
+				// Nodes that have already been popped are never improved
+				// on, so Update always succeeds here.
+				if err := queue.Update(handle, node{next, distance}); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	for id := range expected {
+		if distances[id] != expected[id] {
+			t.Error(id, distances[id])
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package stacks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// A record of an animal leaving the shelter.
+type Adoption struct {
+	Animal  Animal    `json:"animal"`
+	Adopter string    `json:"adopter"`
+	Time    time.Time `json:"time"`
+}
+
+// Record that an animal has been adopted now.
+func (shelter *animalShelter) recordAdoption(animal Animal, adopter string) {
+	shelter.adoptions = append(shelter.adoptions, Adoption{
+		Animal:  animal,
+		Adopter: adopter,
+		Time:    shelter.now(),
+	})
+}
+
+// Return the adoptions from the time from up to, but not including, the time
+// to, oldest first.
+func (shelter *animalShelter) Adoptions(from, to time.Time) []Adoption {
+	return shelter.findAdoptions(from, to, func(Animal) bool { return true })
+}
+
+// Return the adoptions of one type of animal from the time from up to, but
+// not including, the time to, oldest first.
+func (shelter *animalShelter) AdoptionsOf(
+	animalType int, from, to time.Time,
+) []Adoption {
+	return shelter.findAdoptions(from, to, func(animal Animal) bool {
+		return animal.animalType == animalType
+	})
+}
+
+// Return the adoptions in a time range that match a filter.
+func (shelter *animalShelter) findAdoptions(
+	from, to time.Time, match func(Animal) bool,
+) []Adoption {
+	var found []Adoption
+	for _, adoption := range shelter.adoptions {
+		if !adoption.Time.Before(from) && adoption.Time.Before(to) &&
+			match(adoption.Animal) {
+			found = append(found, adoption)
+		}
+	}
+
+	return found
+}
+
+// Names for each animal type in saved shelters.
+var animalTypeNames = map[int]string{
+	Dog: "dog",
+	Cat: "cat",
+}
+
+// The JSON representation of an animal.
+type savedAnimal struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// Encode an animal as JSON.
+func (animal Animal) MarshalJSON() ([]byte, error) {
+	name, ok := animalTypeNames[animal.animalType]
+	if !ok {
+		return nil, fmt.Errorf("unknown animal type %v", animal.animalType)
+	}
+
+	return json.Marshal(savedAnimal{
+		Type: name,
+		Name: animal.name,
+		Age:  animal.age,
+	})
+}
+
+// Decode an animal from JSON.
+func (animal *Animal) UnmarshalJSON(data []byte) error {
+	var saved savedAnimal
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	for animalType, name := range animalTypeNames {
+		if name == saved.Type {
+			*animal = Animal{animalType: animalType, name: saved.Name, age: saved.Age}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown animal type %q", saved.Type)
+}
+
+// The on-disk representation of a shelter.
+type savedShelter struct {
+	Animals   []Animal   `json:"animals"` // in the order they arrived
+	Adoptions []Adoption `json:"adoptions"`
+}
+
+// Write the animals in the shelter and the adoption history as JSON.
+func (shelter *animalShelter) Save(w io.Writer) error {
+	saved := savedShelter{
+		Animals:   []Animal{},
+		Adoptions: shelter.adoptions,
+	}
+	for _, entry := range shelter.animals.Entries() {
+		saved.Animals = append(saved.Animals, entry.Value)
+	}
+	if saved.Adoptions == nil {
+		saved.Adoptions = []Adoption{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(saved)
+}
+
+// Save the shelter to a file, replacing any existing file.
+func (shelter *animalShelter) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := shelter.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Read a shelter previously written by Save. Animals are queued in the order
+// they originally arrived.
+func LoadShelter(r io.Reader) (*animalShelter, error) {
+	var saved savedShelter
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, err
+	}
+
+	shelter := NewAnimalShelter()
+	for _, animal := range saved.Animals {
+		if err := shelter.Enqueue(animal); err != nil {
+			return nil, err
+		}
+	}
+	shelter.adoptions = saved.Adoptions
+
+	return shelter, nil
+}
+
+// Load a shelter from a file.
+func LoadShelterFile(path string) (*animalShelter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadShelter(f)
+}
@@ -0,0 +1,182 @@
+package stacks
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Return a clock for a shelter that starts at midnight on 1 January 2024 and
+// moves forward a day each time it is read.
+func fakeShelterClock() func() time.Time {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(24 * time.Hour)
+		return now
+	}
+}
+
+// Return a shelter with some animals, some of which have been adopted.
+func testShelter(t *testing.T) *animalShelter {
+	shelter := NewAnimalShelter()
+	shelter.now = fakeShelterClock()
+
+	for _, animal := range []Animal{
+		{animalType: Cat, name: "Lottie", age: 12},
+		{animalType: Dog, name: "Spot", age: 5},
+		{animalType: Dog, name: "Lucy", age: 2},
+		{animalType: Cat, name: "Penny", age: 11},
+		{animalType: Dog, name: "Rex", age: 7},
+		{animalType: Cat, name: "Tom", age: 3},
+	} {
+		if err := shelter.Enqueue(animal); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Adopted on 2, 3 and 4 January.
+	for _, adopt := range []func() (Animal, error){
+		func() (Animal, error) { return shelter.AdoptDog("Alice") },
+		func() (Animal, error) { return shelter.AdoptAny("Bob") },
+		func() (Animal, error) { return shelter.AdoptDog("Carol") },
+	} {
+		if _, err := adopt(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return shelter
+}
+
+// Return the names of a list of adoptions.
+func adoptedNames(adoptions []Adoption) []string {
+	var names []string
+	for _, adoption := range adoptions {
+		names = append(names, adoption.Animal.name)
+	}
+
+	return names
+}
+
+// Test querying the adoption history.
+func TestShelterAdoptions(t *testing.T) {
+	shelter := testShelter(t)
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	all := shelter.Adoptions(day(1), day(31))
+	if strings.Join(adoptedNames(all), ",") != "Spot,Lottie,Lucy" {
+		t.Error(adoptedNames(all))
+	}
+
+	if all[0].Adopter != "Alice" || !all[0].Time.Equal(day(2)) {
+		t.Error(all[0])
+	}
+
+	// The end of the range is excluded.
+	names := adoptedNames(shelter.Adoptions(day(3), day(4)))
+	if strings.Join(names, ",") != "Lottie" {
+		t.Error(names)
+	}
+
+	names = adoptedNames(shelter.AdoptionsOf(Dog, day(1), day(31)))
+	if strings.Join(names, ",") != "Spot,Lucy" {
+		t.Error(names)
+	}
+
+	names = adoptedNames(shelter.AdoptionsOf(Cat, day(4), day(31)))
+	if len(names) != 0 {
+		t.Error(names)
+	}
+
+	// Dequeuing also counts as an adoption, with no adopter.
+	animal, err := shelter.DequeueCat()
+	if animal.name != "Penny" || err != nil {
+		t.Error(animal, err)
+	}
+
+	latest := shelter.Adoptions(day(5), day(6))
+	if len(latest) != 1 || latest[0].Adopter != "" {
+		t.Error(latest)
+	}
+}
+
+// Test saving a shelter to a file and loading it again.
+func TestShelterSaveLoad(t *testing.T) {
+	shelter := testShelter(t)
+	path := filepath.Join(t.TempDir(), "shelter.json")
+
+	if err := shelter.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadShelterFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+	original := shelter.Adoptions(from, to)
+	history := loaded.Adoptions(from, to)
+	if len(history) != len(original) {
+		t.Fatal(history)
+	}
+	for i := range history {
+		if history[i].Animal != original[i].Animal ||
+			history[i].Adopter != original[i].Adopter ||
+			!history[i].Time.Equal(original[i].Time) {
+			t.Error(history[i], original[i])
+		}
+	}
+
+	// The remaining animals come out in the order they arrived, both overall
+	// and within each type.
+	cat, err := loaded.DequeueCat()
+	if cat.name != "Penny" || err != nil {
+		t.Error(cat, err)
+	}
+
+	for _, expected := range []string{"Rex", "Tom"} {
+		animal, err := loaded.DequeueAny()
+		if animal.name != expected || err != nil {
+			t.Error(animal, err)
+		}
+	}
+
+	if !loaded.animals.IsEmpty() {
+		t.Error(loaded.animals.Counts())
+	}
+}
+
+// Test that saved shelters are checked when loaded.
+func TestLoadShelterInvalid(t *testing.T) {
+	for _, data := range []string{
+		"",
+		`{"animals": [{"type": "hamster", "name": "Hammy", "age": 1}]}`,
+		`{"animals": "none"}`,
+	} {
+		_, err := LoadShelter(strings.NewReader(data))
+		if err == nil {
+			t.Error(data)
+		}
+	}
+
+	_, err := LoadShelterFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil {
+		t.Error()
+	}
+
+	// An empty shelter round-trips too.
+	var saved strings.Builder
+	if err := NewAnimalShelter().Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	shelter, err := LoadShelter(strings.NewReader(saved.String()))
+	if err != nil || !shelter.animals.IsEmpty() {
+		t.Error(saved.String(), err)
+	}
+}
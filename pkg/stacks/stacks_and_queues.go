@@ -3,58 +3,77 @@ package stacks
 import (
 	"container/list"
 	"errors"
+	"time"
+
+	"golang.org/x/exp/constraints"
 )
 
 // A generic stack must implement four methods: Pop, Push, Peek and IsEmpty.
-type Stack interface {
-	Pop() (interface{}, error)
-	Push(item interface{})
-	Peek() (interface{}, error)
+type Stack[T any] interface {
+	Pop() (T, error)
+	Push(item T)
+	Peek() (T, error)
 	IsEmpty() bool
 }
 
 // A generic queue must implement the same four methods as a stack. Only the
 // ordering of popped elements differs - queues are popped in LIFO order
 // while stacks are FIFO.
-type Queue interface {
-	Add(item interface{})
-	Remove() (interface{}, error)
-	Peek() (interface{}, error)
+type Queue[T any] interface {
+	Add(item T)
+	Remove() (T, error)
+	Peek() (T, error)
 	IsEmpty() bool
 }
 
+// Untyped stacks and queues, for callers that hold values of mixed types.
+type AnyStack = Stack[interface{}]
+type AnyQueue = Queue[interface{}]
+
+// Construct a new empty stack of untyped values.
+func NewAnyStack() AnyStack {
+	return NewBasicStack[interface{}]()
+}
+
+// Construct a new empty queue of untyped values.
+func NewAnyQueue() AnyQueue {
+	return NewBasicQueue[interface{}]()
+}
+
 // Basic implementation of a stack, using a dynamic expanding array slice.
-type BasicStack struct {
-	Data []interface{}
+type BasicStack[T any] struct {
+	Data []T
 }
 
 // Construct a new empty stack.
-func NewBasicStack() *BasicStack {
-	return &BasicStack{}
+func NewBasicStack[T any]() *BasicStack[T] {
+	return &BasicStack[T]{}
 }
 
 // Pop an element from the stack - pops last element from the slice.
-func (stack *BasicStack) Pop() (interface{}, error) {
+func (stack *BasicStack[T]) Pop() (T, error) {
 	if stack.IsEmpty() {
-		return 0, errors.New("Stack is empty")
+		var zero T
+		return zero, errors.New("Stack is empty")
 	}
 
 	newLen := len(stack.Data) - 1
-	var popped interface{}
+	var popped T
 	popped, stack.Data = stack.Data[newLen], stack.Data[:newLen]
 
 	return popped, nil
 }
 
 // Push an element onto the stack - item is appended to the slice.
-func (stack *BasicStack) Push(item interface{}) {
+func (stack *BasicStack[T]) Push(item T) {
 	stack.Data = append(stack.Data, item)
 }
 
 // Peek at the top element in the stack.
-func (stack BasicStack) Peek() (interface{}, error) {
+func (stack BasicStack[T]) Peek() (T, error) {
 	if stack.IsEmpty() {
-		return 0, errors.New("Stack is empty")
+		var zero T
+		return zero, errors.New("Stack is empty")
 	}
 
 	return stack.Data[len(stack.Data)-1], nil
@@ -62,44 +81,49 @@ func (stack BasicStack) Peek() (interface{}, error) {
 
 // Check if the stack is empty, by checking if the data slice has a non-zero
 // length.
-func (stack BasicStack) IsEmpty() bool {
+func (stack BasicStack[T]) IsEmpty() bool {
 	return len(stack.Data) == 0
 }
 
 // Implement a basic queue using a linked list.
-type BasicQueue struct {
+type BasicQueue[T any] struct {
 	list list.List
 }
 
-func NewBasicQueue() *BasicQueue {
-	return &BasicQueue{}
+func NewBasicQueue[T any]() *BasicQueue[T] {
+	return &BasicQueue[T]{}
 }
 
-func (queue *BasicQueue) Add(item interface{}) {
+func (queue *BasicQueue[T]) Add(item T) {
 	queue.list.PushBack(item)
 }
 
-func (queue *BasicQueue) Remove() (interface{}, error) {
+func (queue *BasicQueue[T]) Remove() (T, error) {
 	frontEl := queue.list.Front()
 	if frontEl == nil {
-		return nil, errors.New("Queue is empty")
+		var zero T
+		return zero, errors.New("Queue is empty")
 	}
 
-	retVal := frontEl.Value
+	// Asserting a nil interface to an interface type fails, so a nil stored
+	// in e.g. an AnyQueue comes back as the zero value instead.
+	retVal, _ := frontEl.Value.(T)
 	queue.list.Remove(frontEl)
 	return retVal, nil
 }
 
-func (queue BasicQueue) Peek() (interface{}, error) {
+func (queue BasicQueue[T]) Peek() (T, error) {
 	frontEl := queue.list.Front()
 	if frontEl == nil {
-		return nil, errors.New("Queue is empty")
+		var zero T
+		return zero, errors.New("Queue is empty")
 	}
 
-	return frontEl.Value, nil
+	retVal, _ := frontEl.Value.(T)
+	return retVal, nil
 }
 
-func (queue BasicQueue) IsEmpty() bool {
+func (queue BasicQueue[T]) IsEmpty() bool {
 	return queue.list.Front() == nil
 }
 
@@ -192,170 +216,376 @@ func (stacks multiStacks) getDataIx(stackIx int) int {
 	return (stacks.stackIndices[stackIx] * stacks.numStacks) + stackIx
 }
 
-// A MinStack and its node. This implements a stack interface with the addition
-// of providing O(1) access to the minimum value in the stack. This is achieved
-// by storing an extra min value on each stack node, that keeps track of the
-// minimal value from that node downwards in the stack.
-type MinStackNode struct {
-	data int
-	next *MinStackNode
-	min  int
+// Return the total number of slots in the shared stack data array, used or
+// not.
+func (stacks *multiStacks) TotalCapacity() int {
+	return len(stacks.stackData)
 }
 
-type MinStack struct {
-	top *MinStackNode
+// Stores several stacks in a single array, where each stack owns a
+// contiguous region of the array. Unlike multiStacks, stacks can be of any
+// size: when a stack's region is full it takes space from the nearest stack
+// with some to spare, shifting the stacks in between along. Only when every
+// region is full does the array grow.
+type flexibleMultiStacks[T any] struct {
+	data    []T
+	regions []stackRegion
 }
 
-func (stack *MinStack) Pop() (interface{}, error) {
-	if stack.IsEmpty() {
-		return 0, errors.New("Stack is empty")
+// The part of the shared array owned by a stack. The stack's items are
+// data[start:start+size] and its region is data[start:start+capacity].
+type stackRegion struct {
+	start    int
+	size     int
+	capacity int
+}
+
+// Construct a new flexibleMultiStacks object, with space for a total of
+// capacity items shared evenly between the stacks to begin with.
+func NewFlexibleMultiStacks[T any](numStacks, capacity int) flexibleMultiStacks[T] {
+	if numStacks < 1 {
+		panic("numStacks must be >0")
+	}
+	if capacity < 0 {
+		panic("capacity must be >=0")
 	}
 
-	retVal := stack.top.data
-	stack.top = stack.top.next
+	regions := make([]stackRegion, numStacks)
+	start := 0
+	for i := range regions {
+		regions[i].start = start
+		regions[i].capacity = capacity / numStacks
+		if i < capacity%numStacks {
+			regions[i].capacity++
+		}
+		start += regions[i].capacity
+	}
 
-	return retVal, nil
+	return flexibleMultiStacks[T]{
+		data:    make([]T, capacity),
+		regions: regions,
+	}
 }
 
-func (stack *MinStack) Push(item interface{}) {
-	var newMin int
-	if stack.IsEmpty() {
-		newMin = item.(int)
-	} else {
-		newMin = min(item.(int), stack.top.min)
+// Pops an item off the top of a stack. An error is returned if the stack is
+// empty.
+func (stacks *flexibleMultiStacks[T]) Pop(stackIx int) (T, error) {
+	region := stacks.region(stackIx)
+	if region.size == 0 {
+		var zero T
+		return zero, errors.New("Stack is empty")
 	}
 
-	newNode := MinStackNode{
-		data: item.(int),
-		next: stack.top,
-		min:  newMin,
+	region.size--
+	topIx := region.start + region.size
+	item := stacks.data[topIx]
+
+	var zero T
+	stacks.data[topIx] = zero
+	return item, nil
+}
+
+// Push an item onto the top of a stack. If the stack's region is full it is
+// expanded, so this function should always succeed.
+func (stacks *flexibleMultiStacks[T]) Push(stackIx int, item T) {
+	region := stacks.region(stackIx)
+	if region.size == region.capacity {
+		stacks.expand(stackIx)
 	}
-	stack.top = &newNode
+
+	stacks.data[region.start+region.size] = item
+	region.size++
 }
 
-// Golang has no builtin "min" function...
-func min(x, y int) int {
-	if x < y {
-		return x
+// Return the value at the top of a stack without removing it. An error is
+// returned if the stack is empty.
+func (stacks *flexibleMultiStacks[T]) Peek(stackIx int) (T, error) {
+	region := stacks.region(stackIx)
+	if region.size == 0 {
+		var zero T
+		return zero, errors.New("Stack is empty")
 	}
-	return y
+
+	return stacks.data[region.start+region.size-1], nil
 }
 
-func (stack MinStack) Peek() (interface{}, error) {
-	if stack.IsEmpty() {
-		return 0, errors.New("Stack is empty")
+// Check if a stack is empty.
+func (stacks *flexibleMultiStacks[T]) IsEmpty(stackIx int) bool {
+	return stacks.region(stackIx).size == 0
+}
+
+// Return the number of items in a stack.
+func (stacks *flexibleMultiStacks[T]) Size(stackIx int) int {
+	return stacks.region(stackIx).size
+}
+
+// Return the number of items a stack can hold before its region must be
+// expanded.
+func (stacks *flexibleMultiStacks[T]) Capacity(stackIx int) int {
+	return stacks.region(stackIx).capacity
+}
+
+// Return the total number of slots in the shared array, used or not.
+func (stacks *flexibleMultiStacks[T]) TotalCapacity() int {
+	return len(stacks.data)
+}
+
+// Return the region for a stack, panicking if the stackIx is not within the
+// expected range.
+func (stacks *flexibleMultiStacks[T]) region(stackIx int) *stackRegion {
+	if stackIx < 0 || stackIx >= len(stacks.regions) {
+		panic("stackIx is not within expected range")
 	}
-	return stack.top.data, nil
+
+	return &stacks.regions[stackIx]
 }
 
-func (stack MinStack) IsEmpty() bool {
-	return stack.top == nil
+// Give a full stack's region more space. Half the spare capacity of the
+// nearest stack above with any to spare is taken, or failing that the
+// nearest stack below. If no stack has spare capacity, the array is doubled
+// in size instead.
+func (stacks *flexibleMultiStacks[T]) expand(stackIx int) {
+	for ix := stackIx + 1; ix < len(stacks.regions); ix++ {
+		if spare := stacks.spare(ix); spare > 0 {
+			slots := (spare + 1) / 2
+			stacks.shiftRight(stackIx+1, ix, slots)
+			stacks.regions[ix].capacity -= slots
+			stacks.regions[stackIx].capacity += slots
+			return
+		}
+	}
+
+	for ix := stackIx - 1; ix >= 0; ix-- {
+		if spare := stacks.spare(ix); spare > 0 {
+			slots := (spare + 1) / 2
+			stacks.regions[ix].capacity -= slots
+			stacks.shiftLeft(ix+1, stackIx, slots)
+			stacks.regions[stackIx].capacity += slots
+			return
+		}
+	}
+
+	stacks.grow(stackIx)
 }
 
-func (stack MinStack) Min() (int, error) {
-	if stack.IsEmpty() {
-		return 0, errors.New("Stack is empty")
+// Return the number of unused slots in a stack's region.
+func (stacks *flexibleMultiStacks[T]) spare(stackIx int) int {
+	region := stacks.regions[stackIx]
+	return region.capacity - region.size
+}
+
+// Move the items of stacks first to last, inclusive, along the array to the
+// right. There must be enough free slots after the last stack's items.
+func (stacks *flexibleMultiStacks[T]) shiftRight(first, last, slots int) {
+	for ix := last; ix >= first; ix-- {
+		region := &stacks.regions[ix]
+		copy(
+			stacks.data[region.start+slots:region.start+region.size+slots],
+			stacks.data[region.start:region.start+region.size],
+		)
+		region.start += slots
+	}
+}
+
+// Move the items of stacks first to last, inclusive, along the array to the
+// left. There must be enough free slots before the first stack's items.
+func (stacks *flexibleMultiStacks[T]) shiftLeft(first, last, slots int) {
+	for ix := first; ix <= last; ix++ {
+		region := &stacks.regions[ix]
+		copy(
+			stacks.data[region.start-slots:region.start+region.size-slots],
+			stacks.data[region.start:region.start+region.size],
+		)
+		region.start -= slots
 	}
-	return stack.top.min, nil
+}
+
+// Double the size of the array, sharing the free space out evenly between
+// the stacks. Any remainder goes to the stack that needs it.
+func (stacks *flexibleMultiStacks[T]) grow(stackIx int) {
+	newLen := 2 * len(stacks.data)
+	if newLen == 0 {
+		newLen = len(stacks.regions)
+	}
+
+	used := 0
+	for _, region := range stacks.regions {
+		used += region.size
+	}
+	share := (newLen - used) / len(stacks.regions)
+	remainder := (newLen - used) % len(stacks.regions)
+
+	data := make([]T, newLen)
+	start := 0
+	for ix := range stacks.regions {
+		region := &stacks.regions[ix]
+		copy(data[start:], stacks.data[region.start:region.start+region.size])
+
+		region.start = start
+		region.capacity = region.size + share
+		if ix == stackIx {
+			region.capacity += remainder
+		}
+		start += region.capacity
+	}
+
+	stacks.data = data
+}
+
+// A MinStack implements a stack interface with the addition of providing O(1)
+// access to the minimum value in the stack. It is an AggregateStack that
+// combines items with Min, so each item stores the minimal value from that
+// item downwards in the stack. The zero value is an empty stack.
+type MinStack[T constraints.Ordered] struct {
+	AggregateStack[T]
+}
+
+// Construct a new empty MinStack.
+func NewMinStack[T constraints.Ordered]() *MinStack[T] {
+	return &MinStack[T]{*NewAggregateStack(Min[T])}
+}
+
+// Push an item onto the stack.
+func (stack *MinStack[T]) Push(item T) {
+	// A zero value MinStack gets its combine function on the first push.
+	if stack.combine == nil {
+		stack.combine = Min[T]
+	}
+
+	stack.AggregateStack.Push(item)
+}
+
+// Return the minimum value in the stack.
+func (stack *MinStack[T]) Min() (T, error) {
+	return stack.Aggregate()
 }
 
 // A setOfStacks provides the interface of a single stack while actually
 // being composed of a dynamic number of stacks which are each of a fixed
-// size. Since the size of each internal stack is fixed, it is simplest
-// to implement using arrays.
-type setOfStacks struct {
-	stacks    [][]interface{} // internal stacks implemented as arrays
-	stackSize int             // fixed size of each internal stack
-
-	// Indices that specify the current top stack and position within that top
-	// stack respecively. -1 is used as a special value for the topStack to
-	// indicate an empty stack.
-	topStack int
-	topIx    int
+// size. Each internal stack is a slice whose capacity is the fixed size, so
+// that its length is the number of items it holds.
+type setOfStacks[T any] struct {
+	stacks    [][]T // internal stacks, from bottom to top
+	stackSize int   // fixed size of each internal stack
 }
 
 // Construct a new set of stacks. Note that no arrays are allocated yet -
 // the array backing the first internal stack will be allocated when the
 // first value is pushed.
-func NewSetOfStacks(stackSize int) setOfStacks {
-	return setOfStacks{
-		stacks:    nil,
-		stackSize: stackSize,
-		topStack:  -1,
-		topIx:     stackSize - 1,
+func NewSetOfStacks[T any](stackSize int) setOfStacks[T] {
+	if stackSize < 1 {
+		panic("stackSize must be >0")
 	}
+
+	return setOfStacks[T]{stackSize: stackSize}
 }
 
 // Pop an item from the stack. Returns an error if the stack is empty. If
-// the item popped was the last one in an internal stack, move the top indices
-// to point at the previous stack.
-func (stacks *setOfStacks) Pop() (interface{}, error) {
+// the item popped was the last one in an internal stack, that stack is
+// removed.
+func (stacks *setOfStacks[T]) Pop() (T, error) {
 	if stacks.IsEmpty() {
-		return 0, errors.New("Stack is empty")
+		var zero T
+		return zero, errors.New("Stack is empty")
 	}
 
-	retVal := stacks.stacks[stacks.topStack][stacks.topIx]
+	return stacks.PopAt(len(stacks.stacks)-1, false)
+}
 
-	if stacks.topIx > 0 {
-		stacks.topIx--
-	} else {
-		// Last item in an internal stack is being popped. Reduce the number
-		// of internal stacks by one.
-		stacks.stacks[stacks.topStack] = nil
-		stacks.stacks = stacks.stacks[:stacks.topStack]
-		stacks.topStack--
-		stacks.topIx = stacks.stackSize - 1
+// Pop an item from a specific internal stack, counting from the bottom
+// stack at index 0. Returns an error if there is no such stack.
+//
+// If rollover is true, items are shifted left to fill the gap: the bottom
+// item of each later stack moves to the top of the stack before it, so that
+// every internal stack but the last stays full. Otherwise the other stacks
+// are left alone, and later pushes only fill the top stack. Either way, an
+// internal stack that is left empty is removed.
+func (stacks *setOfStacks[T]) PopAt(stackIx int, rollover bool) (T, error) {
+	if stackIx < 0 || stackIx >= len(stacks.stacks) {
+		var zero T
+		return zero, errors.New("No such stack")
+	}
+
+	// Vacated slots are zeroed so that they don't keep popped values alive.
+	var zero T
+
+	stack := stacks.stacks[stackIx]
+	retVal := stack[len(stack)-1]
+	stack[len(stack)-1] = zero
+	stacks.stacks[stackIx] = stack[:len(stack)-1]
+
+	if rollover {
+		for i := stackIx; i < len(stacks.stacks)-1; i++ {
+			next := stacks.stacks[i+1]
+			stacks.stacks[i] = append(stacks.stacks[i], next[0])
+
+			copy(next, next[1:])
+			next[len(next)-1] = zero
+			stacks.stacks[i+1] = next[:len(next)-1]
+		}
+
+		stackIx = len(stacks.stacks) - 1
+	}
+
+	if len(stacks.stacks[stackIx]) == 0 {
+		copy(stacks.stacks[stackIx:], stacks.stacks[stackIx+1:])
+		stacks.stacks[len(stacks.stacks)-1] = nil
+		stacks.stacks = stacks.stacks[:len(stacks.stacks)-1]
 	}
 
 	return retVal, nil
 }
 
 // Push an item onto the stack. If the current internal stack is full, allocate
-// a new one and update internal indices to point at the new top stack.
-func (stacks *setOfStacks) Push(item interface{}) {
-	if stacks.topIx < stacks.stackSize-1 {
-		stacks.topIx++
-		stacks.stacks[stacks.topStack][stacks.topIx] = item
-	} else {
-		// Create a new internal stack.
-		newStack := make([]interface{}, stacks.stackSize)
-		stacks.stacks = append(stacks.stacks, newStack)
-		stacks.topStack++
-		stacks.topIx = 0
-		newStack[0] = item
+// a new one.
+func (stacks *setOfStacks[T]) Push(item T) {
+	numStacks := len(stacks.stacks)
+	if numStacks == 0 || len(stacks.stacks[numStacks-1]) == stacks.stackSize {
+		stacks.stacks = append(stacks.stacks, make([]T, 0, stacks.stackSize))
+		numStacks++
 	}
+
+	stacks.stacks[numStacks-1] = append(stacks.stacks[numStacks-1], item)
 }
 
 // Peek at the top value in the stack without removing it.
-func (stacks *setOfStacks) Peek() (interface{}, error) {
+func (stacks *setOfStacks[T]) Peek() (T, error) {
 	if stacks.IsEmpty() {
-		return 0, errors.New("Stack is empty")
+		var zero T
+		return zero, errors.New("Stack is empty")
 	}
 
-	return stacks.stacks[stacks.topStack][stacks.topIx], nil
+	top := stacks.stacks[len(stacks.stacks)-1]
+	return top[len(top)-1], nil
 }
 
 // Check if a stack is empty.
-func (stacks *setOfStacks) IsEmpty() bool {
-	return stacks.topStack == -1
+func (stacks *setOfStacks[T]) IsEmpty() bool {
+	return len(stacks.stacks) == 0
+}
+
+// Return the number of internal stacks currently in use.
+func (stacks *setOfStacks[T]) NumStacks() int {
+	return len(stacks.stacks)
 }
 
 // The MyQueue type implements the Queue interface but is actually implemented
 // using two stacks.
-type myQueue struct {
-	inStack  BasicStack
-	outStack BasicStack
+type myQueue[T any] struct {
+	inStack  BasicStack[T]
+	outStack BasicStack[T]
 }
 
 // Construct a new myQueue.
-func NewMyQueue() *myQueue {
-	return &myQueue{}
+func NewMyQueue[T any]() *myQueue[T] {
+	return &myQueue[T]{}
 }
 
 // Remove an item from the front of the queue.
-func (queue *myQueue) Remove() (interface{}, error) {
+func (queue *myQueue[T]) Remove() (T, error) {
 	if queue.IsEmpty() {
-		return 0, errors.New("Queue is empty")
+		var zero T
+		return zero, errors.New("Queue is empty")
 	}
 
 	if !queue.inStack.IsEmpty() {
@@ -369,7 +599,7 @@ func (queue *myQueue) Remove() (interface{}, error) {
 }
 
 // Add an item onto the back of the queue.
-func (queue *myQueue) Add(item interface{}) {
+func (queue *myQueue[T]) Add(item T) {
 	if !queue.outStack.IsEmpty() {
 		err := queue.emptyOutStack()
 		if err != nil {
@@ -381,9 +611,10 @@ func (queue *myQueue) Add(item interface{}) {
 }
 
 // Peek at the front of the queue.
-func (queue *myQueue) Peek() (interface{}, error) {
+func (queue *myQueue[T]) Peek() (T, error) {
 	if queue.IsEmpty() {
-		return 0, errors.New("Queue is empty")
+		var zero T
+		return zero, errors.New("Queue is empty")
 	}
 
 	if !queue.inStack.IsEmpty() {
@@ -397,12 +628,12 @@ func (queue *myQueue) Peek() (interface{}, error) {
 }
 
 // Check if the queue is empty.
-func (queue myQueue) IsEmpty() bool {
+func (queue myQueue[T]) IsEmpty() bool {
 	return queue.inStack.IsEmpty() && queue.outStack.IsEmpty()
 }
 
 // Empty the inStack into the outStack.
-func (queue *myQueue) emptyInStack() error {
+func (queue *myQueue[T]) emptyInStack() error {
 	if !queue.outStack.IsEmpty() {
 		return errors.New("outStack is not empty")
 	}
@@ -420,7 +651,7 @@ func (queue *myQueue) emptyInStack() error {
 }
 
 // Empty the outStack into the inStack.
-func (queue *myQueue) emptyOutStack() error {
+func (queue *myQueue[T]) emptyOutStack() error {
 	if !queue.inStack.IsEmpty() {
 		return errors.New("inStack is not empty")
 	}
@@ -439,26 +670,35 @@ func (queue *myQueue) emptyOutStack() error {
 
 // Sort a stack in-place so that the smallest elements are on top, using only
 // an additional temporary stack.
-func SortStack(stack Stack) {
-	tmpStack := NewBasicStack()
+func SortStack[T constraints.Ordered](stack Stack[T]) {
+	SortStackFunc(stack, Less[T])
+}
+
+// Sort a stack in-place so that the least elements, according to less, are
+// on top, using only an additional temporary stack. Makes repeated passes
+// that each swap adjacent out-of-order elements, so takes O(n^2) time.
+func SortStackFunc[T any](stack Stack[T], less func(a, b T) bool) {
+	var tmpStack Stack[T] = NewBasicStack[T]()
 	isSorted := false
 
 	for !isSorted {
-		sortStackPass(stack, tmpStack, false)
-		isSorted = sortStackPass(tmpStack, stack, true)
+		sortStackPass(stack, tmpStack, less, false)
+		isSorted = sortStackPass(tmpStack, stack, less, true)
 	}
 }
 
 // Make a single pass at sorting inStack into outStack. Adjacent out-of-order
 // elements are swapped.
-func sortStackPass(inStack, outStack Stack, reverse bool) bool {
+func sortStackPass[T any](
+	inStack, outStack Stack[T], less func(a, b T) bool, reverse bool,
+) bool {
 	isSorted := true
 
-	var cmp func(x, y int) bool
+	var cmp func(x, y T) bool
 	if reverse {
-		cmp = func(x, y int) bool { return x >= y }
+		cmp = func(x, y T) bool { return !less(x, y) }
 	} else {
-		cmp = func(x, y int) bool { return x <= y }
+		cmp = func(x, y T) bool { return !less(y, x) }
 	}
 
 	for {
@@ -470,7 +710,7 @@ func sortStackPass(inStack, outStack Stack, reverse bool) bool {
 		outStackTop, err := outStack.Pop()
 		if err != nil {
 			outStack.Push(nextVal)
-		} else if cmp(outStackTop.(int), nextVal.(int)) {
+		} else if cmp(outStackTop, nextVal) {
 			outStack.Push(outStackTop)
 			outStack.Push(nextVal)
 		} else {
@@ -481,6 +721,114 @@ func sortStackPass(inStack, outStack Stack, reverse bool) bool {
 	}
 }
 
+// Sort a stack in-place so that the least elements, according to less, are
+// on top, in O(n log n) time using two additional stacks.
+//
+// This is a bottom-up merge sort. Before each pass the stack holds sorted
+// runs of width elements, least on top, except that the bottom run may be
+// shorter. The runs are dealt out alternately onto the two other stacks,
+// which turns each one upside down, and then merged back in pairs, taking
+// the greatest element first, to give runs twice as wide.
+func MergeSortStack[T any](stack Stack[T], less func(a, b T) bool) {
+	var left, right Stack[T] = NewBasicStack[T](), NewBasicStack[T]()
+
+	// Count the elements.
+	numElements := 0
+	for !stack.IsEmpty() {
+		left.Push(mustPop(stack))
+		numElements++
+	}
+	for !left.IsEmpty() {
+		stack.Push(mustPop(left))
+	}
+
+	for width := 1; width < numElements; width *= 2 {
+		numRuns := (numElements + width - 1) / width
+		lastWidth := numElements - (numRuns-1)*width
+
+		for run := 0; run < numRuns; run++ {
+			runWidth := width
+			if run == numRuns-1 {
+				runWidth = lastWidth
+			}
+
+			if run%2 == 0 {
+				moveElements(stack, left, runWidth)
+			} else {
+				moveElements(stack, right, runWidth)
+			}
+		}
+
+		// With an odd number of runs, the last one has nothing to merge
+		// with, so goes straight back to the bottom of the stack. Otherwise
+		// it is merged with the run before it.
+		rightWidth := width
+		if numRuns%2 == 1 {
+			moveElements(left, stack, lastWidth)
+		} else {
+			rightWidth = lastWidth
+		}
+
+		for !right.IsEmpty() {
+			mergeRuns(left, right, stack, width, rightWidth, less)
+			rightWidth = width
+		}
+	}
+}
+
+// Merge a run of leftWidth elements from the left stack and a run of
+// rightWidth elements from the right stack onto the output stack. Both runs
+// have their greatest element on top, so the merged run ends up with its
+// least element on top.
+func mergeRuns[T any](
+	left, right, out Stack[T],
+	leftWidth, rightWidth int,
+	less func(a, b T) bool,
+) {
+	for leftWidth > 0 && rightWidth > 0 {
+		leftTop := mustPeek(left)
+		rightTop := mustPeek(right)
+
+		if less(leftTop, rightTop) {
+			out.Push(mustPop(right))
+			rightWidth--
+		} else {
+			out.Push(mustPop(left))
+			leftWidth--
+		}
+	}
+
+	moveElements(left, out, leftWidth)
+	moveElements(right, out, rightWidth)
+}
+
+// Move n elements from one stack to another, reversing their order.
+func moveElements[T any](from, to Stack[T], n int) {
+	for i := 0; i < n; i++ {
+		to.Push(mustPop(from))
+	}
+}
+
+// Pop from a stack that is known not to be empty.
+func mustPop[T any](stack Stack[T]) T {
+	val, err := stack.Pop()
+	if err != nil {
+		panic(err)
+	}
+
+	return val
+}
+
+// Peek at a stack that is known not to be empty.
+func mustPeek[T any](stack Stack[T]) T {
+	val, err := stack.Peek()
+	if err != nil {
+		panic(err)
+	}
+
+	return val
+}
+
 // The animal shelter holds both dogs and cats. Animals may be enqueued any
 // time. There are three dequeue operations: DequeueCat/Dog returns the
 // Cat or Dog that has been in the shelter for longest, and DequeueAny
@@ -490,27 +838,19 @@ type Animal struct {
 	animalType int
 	name       string
 	age        int
-	entrySeq   int64
-}
-
-type animalNode struct {
-	animal Animal
-	next   *animalNode
-}
-
-type animalQueue struct {
-	first *animalNode
-	last  *animalNode
 }
 
 type animalShelter struct {
-	dogQueue animalQueue
-	catQueue animalQueue
-	currSeq  int64
+	animals   *CategorisedQueue[int, Animal] // by animal type
+	adoptions []Adoption                     // oldest first
+	now       func() time.Time
 }
 
 func NewAnimalShelter() *animalShelter {
-	return &animalShelter{}
+	return &animalShelter{
+		animals: NewCategorisedQueue[int, Animal](),
+		now:     time.Now,
+	}
 }
 
 const (
@@ -520,99 +860,59 @@ const (
 
 // Adds a new animal to the shelter.
 func (shelter *animalShelter) Enqueue(animal Animal) error {
-	animal.entrySeq = shelter.currSeq
-
-	switch animal.animalType {
-	case Dog:
-		shelter.dogQueue.Add(animal)
-		shelter.currSeq++
-		return nil
-
-	case Cat:
-		shelter.catQueue.Add(animal)
-		shelter.currSeq++
-		return nil
-
-	default:
+	if animal.animalType != Dog && animal.animalType != Cat {
 		return errors.New("Unexpected animal type")
 	}
+
+	shelter.animals.Enqueue(animal.animalType, animal)
+	return nil
 }
 
 // Dequeue the animal that has been in the shelter for longest - either a dog
-// or a cat.
+// or a cat. The adoption is recorded with no adopter.
 func (shelter *animalShelter) DequeueAny() (Animal, error) {
-	nextCat, catErr := shelter.catQueue.Peek()
-	nextDog, dogErr := shelter.dogQueue.Peek()
-
-	// If both queues returned errors that means the shelter is empty.
-	if catErr != nil && dogErr != nil {
-		return Animal{}, errors.New("No animals in shelter")
-	}
-
-	// If the cat queue is empty of the next cat has a higher sequence number
-	// than the next dog, we return the next dog.
-	if catErr != nil || nextCat.entrySeq > nextDog.entrySeq {
-		_, err := shelter.dogQueue.Remove()
-		if err != nil {
-			panic(err)
-		}
-		return nextDog, nil
-	}
-
-	// Otherwise, we return the next cat.
-	_, err := shelter.catQueue.Remove()
-	if err != nil {
-		panic(err)
-	}
-	return nextCat, nil
+	return shelter.AdoptAny("")
 }
 
 // Dequeue the next dog.
 func (shelter *animalShelter) DequeueDog() (Animal, error) {
-	return shelter.dogQueue.Remove()
+	return shelter.AdoptDog("")
 }
 
 // Dequeue the next cat.
 func (shelter *animalShelter) DequeueCat() (Animal, error) {
-	return shelter.catQueue.Remove()
+	return shelter.AdoptCat("")
 }
 
-// Add an animal to a queue.
-func (queue *animalQueue) Add(animal Animal) {
-	newNode := &animalNode{animal: animal}
-	if queue.last != nil {
-		queue.last.next = newNode
-	}
-	queue.last = newNode
-	if queue.first == nil {
-		queue.first = queue.last
+// Adopt out the animal that has been in the shelter for longest, recording
+// who adopted it.
+func (shelter *animalShelter) AdoptAny(adopter string) (Animal, error) {
+	entry, err := shelter.animals.DequeueAny()
+	if err != nil {
+		return Animal{}, errors.New("No animals in shelter")
 	}
+
+	shelter.recordAdoption(entry.Value, adopter)
+	return entry.Value, nil
 }
 
-// Remove an animal from a queue.
-func (queue *animalQueue) Remove() (Animal, error) {
-	if queue.IsEmpty() {
-		return Animal{}, errors.New("Queue is empty")
-	}
+// Adopt out the dog that has been in the shelter for longest.
+func (shelter *animalShelter) AdoptDog(adopter string) (Animal, error) {
+	return shelter.adopt(Dog, adopter)
+}
 
-	animal := queue.first.animal
-	queue.first = queue.first.next
-	if queue.first == nil {
-		queue.last = nil
-	}
-	return animal, nil
+// Adopt out the cat that has been in the shelter for longest.
+func (shelter *animalShelter) AdoptCat(adopter string) (Animal, error) {
+	return shelter.adopt(Cat, adopter)
 }
 
-// Peek at the next element in the queue.
-func (queue animalQueue) Peek() (Animal, error) {
-	if queue.IsEmpty() {
-		return Animal{}, errors.New("Queue is empty")
+// Adopt out the animal of a type that has been in the shelter for longest.
+func (shelter *animalShelter) adopt(animalType int, adopter string) (Animal, error) {
+	entry, err := shelter.animals.Dequeue(animalType)
+	if err != nil {
+		return Animal{}, err
 	}
 
-	return queue.first.animal, nil
-}
-
-// Check if the queue is empty.
-func (queue animalQueue) IsEmpty() bool {
-	return queue.first == nil
+	shelter.recordAdoption(entry.Value, adopter)
+	return entry.Value, nil
 }
@@ -1,10 +1,15 @@
 package stacks
 
-import "testing"
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
 
 // Test the BasicStack type and methods.
 func TestBasicStack(t *testing.T) {
-	stack := NewBasicStack()
+	stack := NewBasicStack[int]()
 	genericStackTest(t, stack)
 }
 
@@ -47,9 +52,166 @@ func TestMultiStacks(t *testing.T) {
 	}
 }
 
+// Test the flexibleMultiStacks type and methods.
+func TestFlexibleMultiStacks(t *testing.T) {
+	stacks := NewFlexibleMultiStacks[int](3, 6)
+
+	for i := 0; i < 3; i++ {
+		if !stacks.IsEmpty(i) || stacks.Capacity(i) != 2 {
+			t.Error(i)
+		}
+
+		_, err := stacks.Pop(i)
+		if err == nil {
+			t.Error()
+		}
+
+		_, err = stacks.Peek(i)
+		if err == nil {
+			t.Error()
+		}
+	}
+
+	// Stack 0 takes space from stack 1, then stack 2.
+	for i := 1; i <= 5; i++ {
+		stacks.Push(0, i)
+	}
+	stacks.Push(2, 6)
+
+	if stacks.Capacity(0) != 5 || stacks.Capacity(1) != 0 ||
+		stacks.Capacity(2) != 1 || stacks.TotalCapacity() != 6 {
+		t.Error(stacks.regions)
+	}
+
+	// Every region is full, so the array doubles.
+	stacks.Push(1, 7)
+	if stacks.TotalCapacity() != 12 || stacks.Size(1) != 1 {
+		t.Error(stacks.regions)
+	}
+
+	val, err := stacks.Pop(0)
+	if val != 5 || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = stacks.Peek(2)
+	if val != 6 || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = stacks.Pop(1)
+	if val != 7 || err != nil {
+		t.Error(val, err)
+	}
+}
+
+// Test random pushes and pops against a slice for each stack, checking the
+// regions account for the whole array after each step.
+func TestFlexibleMultiStacksRandom(t *testing.T) {
+	const numStacks = 4
+	stacks := NewFlexibleMultiStacks[int](numStacks, 0)
+	expected := make([][]int, numStacks)
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 5000; i++ {
+		stackIx := rng.Intn(numStacks)
+
+		// Push more often than pop, and favour the first stack.
+		if rng.Intn(3) == 0 {
+			stackIx = 0
+		}
+
+		if rng.Intn(5) < 3 {
+			stacks.Push(stackIx, i)
+			expected[stackIx] = append(expected[stackIx], i)
+		} else {
+			val, err := stacks.Pop(stackIx)
+			numExpected := len(expected[stackIx])
+			if numExpected == 0 {
+				if err == nil {
+					t.Fatal("popped from empty stack")
+				}
+			} else {
+				if val != expected[stackIx][numExpected-1] || err != nil {
+					t.Fatal(val, err)
+				}
+				expected[stackIx] = expected[stackIx][:numExpected-1]
+			}
+		}
+
+		start := 0
+		for ix, region := range stacks.regions {
+			if region.start != start || region.size > region.capacity ||
+				region.size != len(expected[ix]) {
+				t.Fatal(stacks.regions)
+			}
+			start += region.capacity
+		}
+		if start != stacks.TotalCapacity() {
+			t.Fatal(start, stacks.TotalCapacity())
+		}
+	}
+
+	for stackIx := range expected {
+		for i := len(expected[stackIx]) - 1; i >= 0; i-- {
+			val, err := stacks.Pop(stackIx)
+			if val != expected[stackIx][i] || err != nil {
+				t.Fatal(val, err)
+			}
+		}
+	}
+}
+
+// Compare pushing onto interleaved and flexible stacks, when the stacks
+// grow evenly and when one stack is much deeper than the others. The slots
+// metric is the size of the shared array at the end.
+func BenchmarkMultiStacks(b *testing.B) {
+	const numStacks = 3
+	const numItems = 30000
+
+	workloads := []struct {
+		name      string
+		nextStack func(i int) int
+	}{
+		{"balanced", func(i int) int { return i % numStacks }},
+		{"skewed", func(i int) int {
+			if i%100 == 0 {
+				return i / 100 % numStacks
+			}
+			return 0
+		}},
+	}
+
+	for _, workload := range workloads {
+		b.Run("interleaved/"+workload.name, func(b *testing.B) {
+			var slots int
+			for n := 0; n < b.N; n++ {
+				stacks := NewMultiStacks(numStacks)
+				for i := 0; i < numItems; i++ {
+					stacks.Push(workload.nextStack(i), i)
+				}
+				slots = stacks.TotalCapacity()
+			}
+			b.ReportMetric(float64(slots), "slots")
+		})
+
+		b.Run("flexible/"+workload.name, func(b *testing.B) {
+			var slots int
+			for n := 0; n < b.N; n++ {
+				stacks := NewFlexibleMultiStacks[int](numStacks, numStacks)
+				for i := 0; i < numItems; i++ {
+					stacks.Push(workload.nextStack(i), i)
+				}
+				slots = stacks.TotalCapacity()
+			}
+			b.ReportMetric(float64(slots), "slots")
+		})
+	}
+}
+
 // Test the minStacks type and methods.
 func TestMinStack(t *testing.T) {
-	var stack MinStack
+	var stack MinStack[int]
 	genericStackTest(t, &stack)
 
 	if !stack.IsEmpty() {
@@ -87,9 +249,94 @@ func TestMinStack(t *testing.T) {
 	}
 }
 
+// Test a MinStack of strings.
+func TestMinStackStrings(t *testing.T) {
+	var stack MinStack[string]
+	stack.Push("pear")
+	stack.Push("apple")
+	stack.Push("plum")
+
+	minVal, err := stack.Min()
+	if minVal != "apple" || err != nil {
+		t.Error(minVal, err)
+	}
+
+	stack.Pop()
+	stack.Pop()
+	minVal, err = stack.Min()
+	if minVal != "pear" || err != nil {
+		t.Error(minVal, err)
+	}
+}
+
+// Test the untyped stack and queue, which may hold values of any type.
+func TestAnyStackAndQueue(t *testing.T) {
+	stack := NewAnyStack()
+	stack.Push(1)
+	stack.Push("two")
+
+	val, err := stack.Pop()
+	if val != "two" || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = stack.Pop()
+	if val != 1 || err != nil {
+		t.Error(val, err)
+	}
+
+	queue := NewAnyQueue()
+	queue.Add(1)
+	queue.Add("two")
+
+	val, err = queue.Remove()
+	if val != 1 || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = queue.Remove()
+	if val != "two" || err != nil {
+		t.Error(val, err)
+	}
+
+	if !stack.IsEmpty() || !queue.IsEmpty() {
+		t.Error()
+	}
+}
+
+// Test that nil can be stored in and retrieved from the untyped queue.
+func TestAnyQueueNil(t *testing.T) {
+	queue := NewAnyQueue()
+	queue.Add(nil)
+	queue.Add(1)
+
+	val, err := queue.Peek()
+	if val != nil || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = queue.Remove()
+	if val != nil || err != nil {
+		t.Error(val, err)
+	}
+
+	val, err = queue.Remove()
+	if val != 1 || err != nil {
+		t.Error(val, err)
+	}
+
+	errQueue := NewBasicQueue[error]()
+	errQueue.Add(nil)
+
+	errVal, err := errQueue.Remove()
+	if errVal != nil || err != nil {
+		t.Error(errVal, err)
+	}
+}
+
 // Test the setOfStacks type and methods.
 func TestSetOfStacks(t *testing.T) {
-	stackSet := NewSetOfStacks(3)
+	stackSet := NewSetOfStacks[int](3)
 	genericStackTest(t, &stackSet)
 
 	if !stackSet.IsEmpty() {
@@ -131,8 +378,168 @@ func TestSetOfStacks(t *testing.T) {
 	}
 }
 
+// Test popping from a specific internal stack.
+// Test that PopAt doesn't leave references to popped values in the internal
+// stacks.
+func TestSetOfStacksPopAtClearsSlots(t *testing.T) {
+	for _, rollover := range []bool{false, true} {
+		stackSet := NewSetOfStacks[*int](3)
+		for i := 0; i < 8; i++ {
+			val := i
+			stackSet.Push(&val)
+		}
+
+		if _, err := stackSet.PopAt(0, rollover); err != nil {
+			t.Error(err)
+		}
+		for stackIx, stack := range stackSet.stacks {
+			for _, val := range stack[len(stack):cap(stack)] {
+				if val != nil {
+					t.Error(rollover, stackIx, *val)
+				}
+			}
+		}
+	}
+}
+
+func TestSetOfStacksPopAt(t *testing.T) {
+	stackSet := NewSetOfStacks[int](3)
+	for i := 1; i <= 10; i++ {
+		stackSet.Push(i)
+	}
+
+	if stackSet.NumStacks() != 4 {
+		t.Error(stackSet.NumStacks())
+	}
+
+	_, err := stackSet.PopAt(4, false)
+	if err == nil {
+		t.Error()
+	}
+
+	// Without rollover, the middle stack is left with a gap: [1 2 3] [4 5]
+	// [7 8 9] [10].
+	val, err := stackSet.PopAt(1, false)
+	if val != 6 || err != nil {
+		t.Error(val, err)
+	}
+
+	// Emptying the top stack removes it.
+	val, err = stackSet.PopAt(3, false)
+	if val != 10 || err != nil {
+		t.Error(val, err)
+	}
+
+	if stackSet.NumStacks() != 3 {
+		t.Error(stackSet.NumStacks())
+	}
+
+	// Pushes only fill the top stack: [1 2 3] [4 5] [7 8 9] [11].
+	stackSet.Push(11)
+	if stackSet.NumStacks() != 4 {
+		t.Error(stackSet.NumStacks())
+	}
+
+	for _, expected := range []int{11, 9, 8, 7, 5, 4, 3, 2, 1} {
+		val, err = stackSet.Pop()
+		if val != expected || err != nil {
+			t.Error(val, err)
+		}
+	}
+
+	if !stackSet.IsEmpty() || stackSet.NumStacks() != 0 {
+		t.Error()
+	}
+}
+
+// Test that rollover keeps every internal stack but the last full.
+func TestSetOfStacksRollover(t *testing.T) {
+	stackSet := NewSetOfStacks[int](3)
+	for i := 1; i <= 10; i++ {
+		stackSet.Push(i)
+	}
+
+	// [1 2 3] [4 5 6] [7 8 9] [10] becomes [1 2 3] [4 5 7] [8 9 10].
+	val, err := stackSet.PopAt(1, true)
+	if val != 6 || err != nil {
+		t.Error(val, err)
+	}
+
+	if stackSet.NumStacks() != 3 {
+		t.Error(stackSet.NumStacks())
+	}
+
+	val, err = stackSet.PopAt(1, true)
+	if val != 7 || err != nil {
+		t.Error(val, err)
+	}
+
+	for _, expected := range []int{10, 9, 8, 5, 4, 3, 2, 1} {
+		val, err = stackSet.Pop()
+		if val != expected || err != nil {
+			t.Error(val, err)
+		}
+	}
+}
+
+// Test rollover across many internal stacks against a simple slice.
+func TestSetOfStacksCompaction(t *testing.T) {
+	const stackSize = 4
+	stackSet := NewSetOfStacks[int](stackSize)
+	var expected []int
+
+	for i := 0; i < 100; i++ {
+		stackSet.Push(i)
+		expected = append(expected, i)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for len(expected) > 0 {
+		if rng.Intn(4) == 0 {
+			stackSet.Push(len(expected) + 1000)
+			expected = append(expected, len(expected)+1000)
+		}
+
+		stackIx := rng.Intn(stackSet.NumStacks())
+		val, err := stackSet.PopAt(stackIx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The item popped is the last in the stack at stackIx.
+		ix := (stackIx+1)*stackSize - 1
+		if ix >= len(expected) {
+			ix = len(expected) - 1
+		}
+		if val != expected[ix] {
+			t.Fatal(val, expected[ix])
+		}
+		expected = append(expected[:ix], expected[ix+1:]...)
+
+		numStacks := (len(expected) + stackSize - 1) / stackSize
+		if stackSet.NumStacks() != numStacks {
+			t.Fatal(stackSet.NumStacks(), numStacks)
+		}
+
+		for i, stack := range stackSet.stacks {
+			if i < numStacks-1 && len(stack) != stackSize {
+				t.Fatalf("stack %v has %v items", i, len(stack))
+			}
+			for j, item := range stack {
+				if item != expected[i*stackSize+j] {
+					t.Fatal(stackSet.stacks, expected)
+				}
+			}
+		}
+	}
+
+	if !stackSet.IsEmpty() {
+		t.Error()
+	}
+}
+
 // Generic test for any type that implements the stack interface.
-func genericStackTest(t *testing.T, stack Stack) {
+func genericStackTest(t *testing.T, stack Stack[int]) {
 	if !stack.IsEmpty() {
 		t.Error()
 	}
@@ -179,18 +586,18 @@ func genericStackTest(t *testing.T, stack Stack) {
 
 // Test the BasicQueue type and methods.
 func TestBasicQueue(t *testing.T) {
-	queue := NewBasicQueue()
+	queue := NewBasicQueue[int]()
 	genericQueueTest(t, queue)
 }
 
 // Test the MyQueue type and methods.
 func TestMyQueue(t *testing.T) {
-	queue := NewMyQueue()
+	queue := NewMyQueue[int]()
 	genericQueueTest(t, queue)
 }
 
 // Test the queue interface.
-func genericQueueTest(t *testing.T, queue Queue) {
+func genericQueueTest(t *testing.T, queue Queue[int]) {
 	if !queue.IsEmpty() {
 		t.Error()
 	}
@@ -235,14 +642,14 @@ func genericQueueTest(t *testing.T, queue Queue) {
 
 // Test the SortStack function.
 func TestSortStack(t *testing.T) {
-	stack := NewBasicStack()
+	stack := NewBasicStack[int]()
 	stack.Push(2)
 	stack.Push(1)
 	stack.Push(4)
 	stack.Push(3)
 	stack.Push(5)
 
-	SortStack(stack)
+	SortStack[int](stack)
 
 	for expected := 1; expected < 6; expected++ {
 		val, err := stack.Pop()
@@ -256,6 +663,107 @@ func TestSortStack(t *testing.T) {
 	}
 }
 
+// Test sorting a stack of strings.
+func TestSortStackStrings(t *testing.T) {
+	stack := NewBasicStack[string]()
+	for _, word := range []string{"b", "d", "a", "c"} {
+		stack.Push(word)
+	}
+
+	SortStack[string](stack)
+
+	for _, expected := range []string{"a", "b", "c", "d"} {
+		val, err := stack.Pop()
+		if val != expected || err != nil {
+			t.Error(val, err)
+		}
+	}
+}
+
+// Test sorting a stack with a custom comparator.
+func TestSortStackFunc(t *testing.T) {
+	stack := NewBasicStack[string]()
+	for _, word := range []string{"ccc", "a", "dddd", "bb"} {
+		stack.Push(word)
+	}
+
+	// Longest first.
+	SortStackFunc[string](stack, func(a, b string) bool {
+		return len(a) > len(b)
+	})
+
+	for _, expected := range []string{"dddd", "ccc", "bb", "a"} {
+		val, err := stack.Pop()
+		if val != expected || err != nil {
+			t.Error(val, err)
+		}
+	}
+}
+
+// Test both sorts on stacks of many sizes against sort.Ints.
+func TestMergeSortStack(t *testing.T) {
+	sorts := map[string]func(Stack[int], func(a, b int) bool){
+		"bubble": SortStackFunc[int],
+		"merge":  MergeSortStack[int],
+	}
+	rng := rand.New(rand.NewSource(1))
+
+	for name, sortStack := range sorts {
+		for _, size := range []int{0, 1, 2, 3, 4, 5, 7, 8, 9, 17, 100, 1000} {
+			stack := NewBasicStack[int]()
+			var expected []int
+			for i := 0; i < size; i++ {
+				val := rng.Intn(size + 1)
+				stack.Push(val)
+				expected = append(expected, val)
+			}
+			sort.Ints(expected)
+
+			sortStack(stack, Less[int])
+
+			for _, val := range expected {
+				top, err := stack.Pop()
+				if top != val || err != nil {
+					t.Fatalf("%v sort, size %v: got %v, expected %v", name, size, top, val)
+				}
+			}
+			if !stack.IsEmpty() {
+				t.Fatalf("%v sort, size %v: stack not empty", name, size)
+			}
+		}
+	}
+}
+
+// Compare the bubble and merge sorts on random stacks. The bubble sort is
+// O(n^2), so is left out at 100k elements, where it would take minutes.
+func BenchmarkSortStack(b *testing.B) {
+	sorts := []struct {
+		name      string
+		sortStack func(Stack[int], func(a, b int) bool)
+		maxSize   int
+	}{
+		{"bubble", SortStackFunc[int], 10000},
+		{"merge", MergeSortStack[int], 100000},
+	}
+
+	for _, s := range sorts {
+		for size := 1000; size <= s.maxSize; size *= 10 {
+			b.Run(fmt.Sprintf("%v/n=%v", s.name, size), func(b *testing.B) {
+				rng := rand.New(rand.NewSource(1))
+				values := rng.Perm(size)
+
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					stack := &BasicStack[int]{Data: append([]int(nil), values...)}
+					b.StartTimer()
+
+					s.sortStack(stack, Less[int])
+				}
+			})
+		}
+	}
+}
+
 // Test the animalShelter type and methods.
 func TestAnimalShelter(t *testing.T) {
 	shelter := NewAnimalShelter()
@@ -19,7 +19,7 @@ type Graph struct {
 }
 
 func (graph Graph) RouteExists(nodeS, nodeE *GraphNode) bool {
-	queue := stacks.NewBasicQueue()
+	queue := stacks.NewAnyQueue()
 	for i := range graph.nodes {
 		graph.nodes[i].visited = false
 	}